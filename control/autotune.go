@@ -0,0 +1,462 @@
+package control
+
+import (
+	"errors"
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+var (
+	// returned when a relay experiment ends before a sustained oscillation is measured
+	ErrNoOscillation = errors.New("control: relay experiment did not produce a sustained oscillation")
+
+	// returned when step data does not contain a usable response
+	ErrNoResponse = errors.New("control: step experiment did not produce a usable response")
+)
+
+// any scalar system driven by an input and a timestep
+//
+// FirstOrder, SecondOrder and Siso satisfy this interface
+type Plant[T c.Float] interface {
+	Compute(u, dt T) T
+}
+
+// select a tuning rule based on the ultimate gain ku and period pu
+type TuningRule int
+
+const (
+	// ziegler nichols proportional only, kp = 0.5*ku
+	ZieglerNicholsP TuningRule = iota
+
+	// ziegler nichols pi, kp = 0.45*ku, ti = pu/1.2
+	ZieglerNicholsPI
+
+	// ziegler nichols classic pid, kp = 0.6*ku, ti = pu/2, td = pu/8
+	ZieglerNicholsPID
+
+	// tyreus luyben pi, kp = ku/3.2, ti = 2.2*pu
+	TyreusLuybenPI
+
+	// tyreus luyben pid, kp = ku/2.2, ti = 2.2*pu, td = pu/6.3
+	TyreusLuybenPID
+
+	// pessen integral rule, kp = 0.7*ku, ti = 0.4*pu, td = 0.15*pu
+	PessenIntegral
+
+	// some overshoot, kp = 0.33*ku, ti = 0.5*pu, td = 0.33*pu
+	SomeOvershoot
+
+	// no overshoot, kp = 0.2*ku, ti = 0.5*pu, td = 0.33*pu
+	NoOvershoot
+)
+
+// compute pid gains from the ultimate gain ku and ultimate period pu
+//
+// gains follow the parallel form used by Pid, ki = kp/ti and kd = kp*td
+//
+// time: O(1)
+func TuneUltimate[T c.Float](ku, pu T, rule TuningRule) (kp, ki, kd T) {
+	var p, ti, td float64
+	k, u := float64(ku), float64(pu)
+
+	switch rule {
+	case ZieglerNicholsP:
+		p = 0.5 * k
+	case ZieglerNicholsPI:
+		p, ti = 0.45*k, u/1.2
+	case ZieglerNicholsPID:
+		p, ti, td = 0.6*k, u/2, u/8
+	case TyreusLuybenPI:
+		p, ti = k/3.2, 2.2*u
+	case TyreusLuybenPID:
+		p, ti, td = k/2.2, 2.2*u, u/6.3
+	case PessenIntegral:
+		p, ti, td = 0.7*k, 0.4*u, 0.15*u
+	case SomeOvershoot:
+		p, ti, td = 0.33*k, 0.5*u, 0.33*u
+	case NoOvershoot:
+		p, ti, td = 0.2*k, 0.5*u, 0.33*u
+	}
+
+	kp = T(p)
+	if ti > 0 {
+		ki = T(p / ti)
+	}
+	kd = T(p * td)
+	return kp, ki, kd
+}
+
+// ==========
+// relay test
+// ==========
+
+// configure a relay feedback experiment
+type RelayConfig[T c.Float] struct {
+	// process value around which the relay switches
+	Setpoint T
+
+	// relay half amplitude, the input swings between bias-amplitude and bias+amplitude
+	Amplitude T
+
+	// input offset that keeps the plant near its operating point
+	Bias T
+
+	// error band that must be crossed before the relay switches, rejects noise
+	Hysteresis T
+
+	// number of consecutive full oscillation cycles to average, 3 if not positive
+	Cycles int
+
+	// relative spread allowed between the averaged cycle amplitudes and periods, 0.05 if not positive
+	//
+	// cycles are discarded until the oscillation settles within this tolerance
+	Tolerance T
+}
+
+// store the outcome of a relay experiment
+type RelayResult[T c.Float] struct {
+	// ultimate gain
+	Ku T
+
+	// ultimate period in seconds
+	Pu T
+
+	// measured half peak to peak amplitude of the process value
+	Amplitude T
+}
+
+// compute pid gains from the measured ultimate point
+//
+// time: O(1)
+func (r RelayResult[T]) Gains(rule TuningRule) (kp, ki, kd T) {
+	return TuneUltimate(r.Ku, r.Pu, rule)
+}
+
+// drive a process with a relay to find its ultimate gain and period
+//
+// feed each process value to compute and apply the returned input to the process
+//
+// cycles are discarded while the oscillation is still growing or decaying
+//
+// this type is not safe for concurrent use
+type Relay[T c.Float] struct {
+	cfg RelayConfig[T]
+
+	t    float64
+	high bool
+	init bool
+	done bool
+
+	lastRise float64
+	rises    int
+	lo, hi   float64
+
+	// ring of the most recent cycle periods and amplitudes
+	periods []float64
+	amps    []float64
+	next    int
+	count   int
+}
+
+// create a relay experiment
+func NewRelay[T c.Float](cfg RelayConfig[T]) *Relay[T] {
+	if cfg.Cycles <= 0 {
+		cfg.Cycles = 3
+	}
+	if cfg.Hysteresis < 0 {
+		cfg.Hysteresis = -cfg.Hysteresis
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 0.05
+	}
+	return &Relay[T]{
+		cfg:     cfg,
+		periods: make([]float64, cfg.Cycles),
+		amps:    make([]float64, cfg.Cycles),
+	}
+}
+
+// reset the experiment
+func (r *Relay[T]) Reset() {
+	*r = Relay[T]{cfg: r.cfg, periods: r.periods, amps: r.amps}
+}
+
+// compute the relay input for the process value pv and timestep dt
+//
+// return the bias once the experiment is done or if dt is not positive
+//
+// time: O(1)
+func (r *Relay[T]) Compute(pv, dt T) T {
+	if dt <= 0 || r.Done() {
+		return r.cfg.Bias
+	}
+
+	e := float64(r.cfg.Setpoint - pv)
+	eps := float64(r.cfg.Hysteresis)
+	y := float64(pv)
+
+	if !r.init {
+		r.high = e >= 0
+		r.lo, r.hi = y, y
+		r.init = true
+	}
+
+	r.t += float64(dt)
+	r.lo = min(r.lo, y)
+	r.hi = max(r.hi, y)
+
+	switch {
+	case r.high && e < -eps:
+		r.high = false
+
+	case !r.high && e > eps:
+		r.high = true
+		r.rise()
+	}
+
+	if r.high {
+		return r.cfg.Bias + r.cfg.Amplitude
+	}
+	return r.cfg.Bias - r.cfg.Amplitude
+}
+
+func (r *Relay[T]) rise() {
+	r.rises++
+
+	// the first rise closes the initial transient, later rises close full cycles
+	if r.rises > 1 {
+		r.periods[r.next] = r.t - r.lastRise
+		r.amps[r.next] = (r.hi - r.lo) / 2
+		r.next = (r.next + 1) % len(r.periods)
+		r.count = min(r.count+1, len(r.periods))
+		r.done = r.count == len(r.periods) && r.settled()
+	}
+
+	r.lastRise = r.t
+	r.lo, r.hi = math.Inf(1), math.Inf(-1)
+}
+
+func (r *Relay[T]) settled() bool {
+	tol := float64(r.cfg.Tolerance)
+	pLo, pHi := r.periods[0], r.periods[0]
+	aLo, aHi := r.amps[0], r.amps[0]
+	for i := range r.periods {
+		pLo, pHi = min(pLo, r.periods[i]), max(pHi, r.periods[i])
+		aLo, aHi = min(aLo, r.amps[i]), max(aHi, r.amps[i])
+	}
+	return pHi-pLo <= tol*pHi && aHi-aLo <= tol*aHi
+}
+
+// report whether a settled oscillation has been measured
+//
+// time: O(1)
+func (r *Relay[T]) Done() bool {
+	return r.done
+}
+
+// return the ultimate gain and period estimated from the describing function
+//
+//	ku = 4*d / (pi*sqrt(a^2 - eps^2))
+//
+// return ErrNoOscillation if the experiment is not done
+//
+// time: O(c) where c is the number of averaged cycles
+func (r *Relay[T]) Result() (RelayResult[T], error) {
+	if !r.Done() {
+		return RelayResult[T]{}, ErrNoOscillation
+	}
+
+	var a, pu float64
+	for i := range r.periods {
+		a += r.amps[i]
+		pu += r.periods[i]
+	}
+	a /= float64(len(r.amps))
+	pu /= float64(len(r.periods))
+
+	eps := float64(r.cfg.Hysteresis)
+	den := math.Sqrt(max(a*a-eps*eps, 0))
+	if den == 0 || pu <= 0 {
+		return RelayResult[T]{}, ErrNoOscillation
+	}
+
+	ku := 4 * float64(r.cfg.Amplitude) / (math.Pi * den)
+	return RelayResult[T]{Ku: T(ku), Pu: T(pu), Amplitude: T(a)}, nil
+}
+
+// run a relay experiment against a simulated plant starting from rest
+//
+// return ErrNoOscillation if no sustained oscillation is found within duration
+//
+// time: O(duration/dt)
+func RelayTest[T c.Float](plant Plant[T], cfg RelayConfig[T], dt, duration T) (RelayResult[T], error) {
+	if dt <= 0 {
+		return RelayResult[T]{}, ErrNoOscillation
+	}
+
+	r := NewRelay(cfg)
+
+	var y T
+	for t := T(0); t < duration && !r.Done(); t += dt {
+		u := r.Compute(y, dt)
+		y = plant.Compute(u, dt)
+	}
+
+	return r.Result()
+}
+
+// =====
+// fopdt
+// =====
+
+// describe a first order plus dead time model
+//
+//	g(s) = k*e^(-theta*s) / (tau*s + 1)
+type Fopdt[T c.Float] struct {
+	K     T
+	Tau   T
+	Theta T
+}
+
+// compute pi gains with the skogestad simc rule
+//
+// tc is the desired closed loop time constant, tc = theta gives tight control with good robustness
+//
+// kp = tau / (k*(tc + theta)), ti = min(tau, 4*(tc + theta))
+//
+// return zeros if the model gain is zero or tc + theta is not positive
+//
+// time: O(1)
+func (m Fopdt[T]) Simc(tc T) (kp, ki, kd T) {
+	if m.K == 0 || tc+m.Theta <= 0 {
+		return 0, 0, 0
+	}
+
+	kp = m.Tau / (m.K * (tc + m.Theta))
+	ti := min(m.Tau, 4*(tc+m.Theta))
+	if ti > 0 {
+		ki = kp / ti
+	}
+	return kp, ki, 0
+}
+
+// return the ultimate gain and period of the model
+//
+// solve atan(w*tau) + w*theta = pi for the phase crossover frequency w
+//
+// return +inf and 0 if theta is not positive, since the loop never reaches -180 degrees
+//
+// time: O(1)
+func (m Fopdt[T]) Ultimate() (ku, pu T) {
+	k, tau, theta := float64(m.K), float64(m.Tau), float64(m.Theta)
+	if theta <= 0 || k == 0 {
+		return T(math.Inf(1)), 0
+	}
+
+	phase := func(w float64) float64 {
+		return math.Atan(w*tau) + w*theta - math.Pi
+	}
+
+	// phase is monotonic in w, crossover lies below pi/theta
+	lo, hi := 0.0, math.Pi/theta
+	for range 100 {
+		mid := (lo + hi) / 2
+		if phase(mid) < 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	w := (lo + hi) / 2
+	return T(math.Sqrt(1+w*w*tau*tau) / math.Abs(k)), T(2 * math.Pi / w)
+}
+
+// compute pid gains from the model ultimate point
+//
+// time: O(1)
+func (m Fopdt[T]) Gains(rule TuningRule) (kp, ki, kd T) {
+	ku, pu := m.Ultimate()
+	return TuneUltimate(ku, pu, rule)
+}
+
+// fit a fopdt model to a recorded step response with the two point method
+//
+// t and y hold sample times and process values, the step of size amp is applied at t[0]
+//
+// the final value is the mean of the last tenth of the samples
+//
+// tau = 1.5*(t63 - t28), theta = t63 - tau
+//
+// return ErrNoResponse if the samples do not contain a usable response
+//
+// time: O(n)
+func FitFopdtStep[T c.Float](t, y []T, amp T) (Fopdt[T], error) {
+	n := len(y)
+	if n < 3 || len(t) != n || amp == 0 {
+		return Fopdt[T]{}, ErrNoResponse
+	}
+
+	y0 := float64(y[0])
+
+	tail := max(1, n/10)
+	var yss float64
+	for _, v := range y[n-tail:] {
+		yss += float64(v)
+	}
+	yss /= float64(tail)
+
+	dy := yss - y0
+	if dy == 0 {
+		return Fopdt[T]{}, ErrNoResponse
+	}
+
+	cross := func(frac float64) (float64, bool) {
+		level := y0 + frac*dy
+		for i := 1; i < n; i++ {
+			a, b := float64(y[i-1]), float64(y[i])
+			if (a-level)*(b-level) <= 0 && a != b {
+				ta, tb := float64(t[i-1]), float64(t[i])
+				return ta + (level-a)/(b-a)*(tb-ta), true
+			}
+		}
+		return 0, false
+	}
+
+	t28, ok1 := cross(0.283)
+	t63, ok2 := cross(0.632)
+	if !ok1 || !ok2 {
+		return Fopdt[T]{}, ErrNoResponse
+	}
+
+	tau := 1.5 * (t63 - t28)
+	theta := max(0, t63-float64(t[0])-tau)
+
+	return Fopdt[T]{K: T(dy / float64(amp)), Tau: T(tau), Theta: T(theta)}, nil
+}
+
+// apply a step of size amp to a simulated plant starting from rest and fit a fopdt model
+//
+// return ErrNoResponse if the response is unusable within duration
+//
+// time: O(duration/dt)
+func IdentifyFopdt[T c.Float](plant Plant[T], amp, dt, duration T) (Fopdt[T], error) {
+	if dt <= 0 || duration <= 0 {
+		return Fopdt[T]{}, ErrNoResponse
+	}
+
+	n := int(duration/dt) + 1
+	ts := make([]T, 0, n)
+	ys := make([]T, 0, n)
+
+	// sample the resting output at t = 0, then apply the step
+	ts = append(ts, 0)
+	ys = append(ys, plant.Compute(0, dt))
+	for i := 1; i < n; i++ {
+		ts = append(ts, T(i)*dt)
+		ys = append(ys, plant.Compute(amp, dt))
+	}
+
+	return FitFopdtStep(ts, ys, amp)
+}
@@ -0,0 +1,166 @@
+package control
+
+import (
+	"errors"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// returned when a cascade has no stages or a stage has no controller
+var ErrCascade = errors.New("control: cascade needs at least one stage and every stage needs a controller")
+
+// describe one loop of a cascade
+type CascadeStage[T c.Float] struct {
+	// feedback controller driven by setpoint minus measurement
+	Controller Controller[T]
+
+	// optional system driven by the stage setpoint whose output is added to the controller output
+	//
+	// StaticFeedforward and DerivativeFeedforward are static and dynamic choices,
+	// any Plant such as FirstOrder or Siso works as a dynamic feedforward filter
+	Feedforward Plant[T]
+
+	// output saturation limits, if both are zero the output is not limited
+	//
+	// while the output saturates, a controller with a Track(T) method such as Pid is told
+	// the feedback part that was actually applied, so its integral does not wind up
+	Min, Max T
+
+	// number of steps the next inner stage runs per step of this stage, 1 if not positive
+	//
+	// ignored on the innermost stage
+	Ratio int
+}
+
+// chain feedback loops so each stage produces the setpoint of the next one
+//
+// the first stage is the outermost, for instance position, velocity and current,
+// and the last stage drives the actuator
+//
+// compute is called at the innermost rate, outer stages run every product of the inner
+// ratios and hold their output in between
+//
+// this type is not safe for concurrent use
+type Cascade[T c.Float] struct {
+	stages []CascadeStage[T]
+
+	// period of each stage in innermost steps
+	period []int
+
+	sp   []T
+	out  []T
+	tick int
+}
+
+// create a cascade from its stages, outermost first
+//
+// return ErrCascade if there are no stages or a stage has no controller
+//
+// time: O(n)
+func NewCascade[T c.Float](stages ...CascadeStage[T]) (*Cascade[T], error) {
+	if len(stages) == 0 {
+		return nil, ErrCascade
+	}
+	for _, s := range stages {
+		if s.Controller == nil {
+			return nil, ErrCascade
+		}
+	}
+
+	n := len(stages)
+	period := make([]int, n)
+	period[n-1] = 1
+	for i := n - 2; i >= 0; i-- {
+		period[i] = period[i+1] * max(1, stages[i].Ratio)
+	}
+
+	return &Cascade[T]{
+		stages: append([]CascadeStage[T](nil), stages...),
+		period: period,
+		sp:     make([]T, n),
+		out:    make([]T, n),
+	}, nil
+}
+
+// return the number of stages
+//
+// time: O(1)
+func (cs *Cascade[T]) Len() int {
+	return len(cs.stages)
+}
+
+// reset the rate division, the held outputs and every controller and feedforward with a Reset method
+//
+// time: O(n)
+func (cs *Cascade[T]) Reset() {
+	cs.tick = 0
+	clear(cs.sp)
+	clear(cs.out)
+
+	for _, s := range cs.stages {
+		if r, ok := s.Controller.(interface{ Reset() }); ok {
+			r.Reset()
+		}
+		if r, ok := s.Feedforward.(interface{ Reset() }); ok {
+			r.Reset()
+		}
+	}
+}
+
+// return the setpoint each stage last ran with, outermost first
+//
+// time: O(n)
+func (cs *Cascade[T]) Setpoints() []T {
+	return append([]T(nil), cs.sp...)
+}
+
+// return the held output of each stage, outermost first
+//
+// time: O(n)
+func (cs *Cascade[T]) Outputs() []T {
+	return append([]T(nil), cs.out...)
+}
+
+// advance the cascade by one innermost step of dt and return the actuator command
+//
+// ref is the setpoint of the outermost stage and meas holds one measurement per stage, outermost first
+//
+// return the held command if dt is not positive or meas does not have one entry per stage
+//
+// time: O(n)
+func (cs *Cascade[T]) Compute(ref T, meas []T, dt T) T {
+	n := len(cs.stages)
+	if dt <= 0 || len(meas) != n {
+		return cs.out[n-1]
+	}
+
+	sp := ref
+	for i, s := range cs.stages {
+		if cs.tick%cs.period[i] == 0 {
+			h := dt * T(cs.period[i])
+
+			var ff T
+			if s.Feedforward != nil {
+				ff = s.Feedforward.Compute(sp, h)
+			}
+			u := s.Controller.Compute(sp-meas[i], h) + ff
+
+			// report the applied feedback part so the controller does not wind up
+			if s.Min != 0 || s.Max != 0 {
+				if sat := min(s.Max, max(s.Min, u)); sat != u {
+					if tr, ok := s.Controller.(interface{ Track(T) }); ok {
+						tr.Track(sat - ff)
+					}
+					u = sat
+				}
+			}
+
+			cs.sp[i] = sp
+			cs.out[i] = u
+		}
+		sp = cs.out[i]
+	}
+
+	cs.tick = (cs.tick + 1) % cs.period[0]
+	return cs.out[n-1]
+}
@@ -0,0 +1,65 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// generate a sinusoidal frequency sweep
+//
+// after the sweep duration the signal keeps oscillating at the final frequency
+type Chirp[T c.Float] struct {
+	amp      float64
+	f0, f1   float64
+	duration float64
+	log      bool
+}
+
+// create a linear chirp sweeping from f0 to f1 hz over duration seconds
+//
+// the instantaneous frequency is f0 + (f1 - f0)*t/duration
+func NewChirp[T c.Float](amp, f0, f1, duration T) Chirp[T] {
+	return Chirp[T]{
+		amp:      float64(amp),
+		f0:       float64(f0),
+		f1:       float64(f1),
+		duration: float64(duration),
+	}
+}
+
+// create an exponential chirp sweeping from f0 to f1 hz over duration seconds
+//
+// the instantaneous frequency is f0*(f1/f0)^(t/duration), which spends equal time per decade
+//
+// falls back to a linear sweep if f0 or f1 are not positive
+func NewLogChirp[T c.Float](amp, f0, f1, duration T) Chirp[T] {
+	ch := NewChirp(amp, f0, f1, duration)
+	ch.log = f0 > 0 && f1 > 0 && f0 != f1
+	return ch
+}
+
+// compute the reference value at time t
+//
+// time: O(1)
+func (ch Chirp[T]) Compute(t T) T {
+	tf := float64(t)
+	if tf < 0 {
+		return 0
+	}
+	if ch.duration <= 0 {
+		return T(ch.amp * math.Sin(2*math.Pi*ch.f1*tf))
+	}
+
+	tau := min(tf, ch.duration)
+	var cycles float64
+	if ch.log {
+		k := ch.f1 / ch.f0
+		cycles = ch.f0 * ch.duration / math.Log(k) * (math.Pow(k, tau/ch.duration) - 1)
+	} else {
+		cycles = ch.f0*tau + (ch.f1-ch.f0)/(2*ch.duration)*tau*tau
+	}
+	cycles += ch.f1 * (tf - tau)
+
+	return T(ch.amp * math.Sin(2*math.Pi*cycles))
+}
@@ -0,0 +1,1721 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vistormu/go-dsa/csv"
+	"github.com/vistormu/go-dsa/system"
+)
+
+func firstOrderStep(k, tau, t float64) float64 {
+	return k * (1 - math.Exp(-t/tau))
+}
+
+func secondOrderStep(k, wn, zeta, t float64) float64 {
+	wd := wn * math.Sqrt(1-zeta*zeta)
+	phi := math.Acos(zeta)
+	return k * (1 - math.Exp(-zeta*wn*t)/math.Sqrt(1-zeta*zeta)*math.Sin(wd*t+phi))
+}
+
+func TestFirstOrderIntegrators(t *testing.T) {
+	tests := []struct {
+		method Integrator
+		tol    float64
+	}{
+		{ForwardEuler, 2e-2},
+		{BackwardEuler, 2e-2},
+		{Tustin, 1e-3},
+		{RK4, 1e-6},
+		{ZOH, 1e-9},
+	}
+
+	k, tau, dt := 2.0, 0.5, 0.01
+
+	for _, tt := range tests {
+		t.Run(tt.method.String(), func(t *testing.T) {
+			s := NewFirstOrder(k, tau).WithIntegrator(tt.method)
+
+			for i := 1; i <= 300; i++ {
+				y := s.Compute(1, dt)
+				want := firstOrderStep(k, tau, float64(i)*dt)
+				if math.Abs(y-want) > tt.tol {
+					t.Fatalf("step %d: got %v, want %v", i, y, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSecondOrderIntegrators(t *testing.T) {
+	tests := []struct {
+		method Integrator
+		tol    float64
+	}{
+		{ForwardEuler, 5e-2},
+		{BackwardEuler, 5e-2},
+		{Tustin, 1e-3},
+		{RK4, 1e-5},
+		{ZOH, 1e-9},
+	}
+
+	k, wn, zeta, dt := 1.5, 10.0, 0.3, 0.001
+
+	for _, tt := range tests {
+		t.Run(tt.method.String(), func(t *testing.T) {
+			s := NewSecondOrder(k, wn, zeta).WithIntegrator(tt.method)
+
+			for i := 1; i <= 2000; i++ {
+				y := s.Compute(1, dt)
+				want := secondOrderStep(k, wn, zeta, float64(i)*dt)
+				if math.Abs(y-want) > tt.tol {
+					t.Fatalf("step %d: got %v, want %v", i, y, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStiffStability(t *testing.T) {
+	// dt is far above the euler stability limit of 2*tau
+	tau, dt := 0.001, 0.01
+
+	for _, method := range []Integrator{BackwardEuler, Tustin, ZOH} {
+		t.Run(method.String(), func(t *testing.T) {
+			s := NewFirstOrder(1.0, tau).WithIntegrator(method)
+
+			var y float64
+			for range 500 {
+				y = s.Compute(1, dt)
+			}
+			if math.Abs(y-1) > 1e-6 {
+				t.Fatalf("got %v, want 1", y)
+			}
+		})
+	}
+}
+
+func TestSingularImplicitStep(t *testing.T) {
+	// unstable pole with a*dt = 1 for backward euler and a*dt = 2 for tustin
+	for _, tc := range []struct {
+		method Integrator
+		dt     float64
+	}{{BackwardEuler, 1}, {Tustin, 2}} {
+		s := NewFirstOrderSS(1.0, 1, 1, 0).WithIntegrator(tc.method)
+		euler := NewFirstOrderSS(1.0, 1, 1, 0)
+		for i := range 5 {
+			y, want := s.Compute(1, tc.dt), euler.Compute(1, tc.dt)
+			if math.IsInf(y, 0) || math.IsNaN(y) || y != want {
+				t.Fatalf("%v step %d: got %v, want forward euler %v", tc.method, i, y, want)
+			}
+		}
+	}
+}
+
+func TestStateSpaceMatchesSecondOrder(t *testing.T) {
+	k, wn, zeta, dt := 1.0, 4.0, 0.5, 0.01
+
+	ss, err := NewStateSpace(
+		[][]float64{{0, 1}, {-wn * wn, -2 * zeta * wn}},
+		[][]float64{{0}, {k * wn * wn}},
+		[][]float64{{1, 0}},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range []Integrator{ForwardEuler, Tustin, ZOH} {
+		t.Run(method.String(), func(t *testing.T) {
+			ss.Reset()
+			ss.WithIntegrator(method)
+			so := NewSecondOrder(k, wn, zeta).WithIntegrator(method)
+
+			for i := range 500 {
+				got := ss.Compute([]float64{1}, dt)[0]
+				want := so.Compute(1, dt)
+				if math.Abs(got-want) > 1e-9 {
+					t.Fatalf("step %d: got %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStateSpaceMimo(t *testing.T) {
+	// two decoupled first order plants with a direct feedthrough on the second output
+	ss, err := NewStateSpace(
+		[][]float64{{-1, 0}, {0, -10}},
+		[][]float64{{1, 0}, {0, 20}},
+		[][]float64{{1, 0}, {0, 1}},
+		[][]float64{{0, 0}, {0, 0.5}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.WithIntegrator(ZOH)
+
+	if ss.States() != 2 || ss.Inputs() != 2 || ss.Outputs() != 2 {
+		t.Fatalf("unexpected dimensions %d %d %d", ss.States(), ss.Inputs(), ss.Outputs())
+	}
+
+	dt := 0.01
+	var y []float64
+	for i := 1; i <= 100; i++ {
+		y = ss.Compute([]float64{1, 1}, dt)
+	}
+
+	if want := firstOrderStep(1, 1, 1); math.Abs(y[0]-want) > 1e-9 {
+		t.Fatalf("y0 = %v, want %v", y[0], want)
+	}
+	if want := firstOrderStep(2, 0.1, 1) + 0.5; math.Abs(y[1]-want) > 1e-9 {
+		t.Fatalf("y1 = %v, want %v", y[1], want)
+	}
+
+	if !ss.SetState([]float64{3, 4}) {
+		t.Fatal("SetState rejected a valid state")
+	}
+	if x := ss.State(); x[0] != 3 || x[1] != 4 {
+		t.Fatalf("State = %v, want [3 4]", x)
+	}
+	if ss.SetState([]float64{1}) {
+		t.Fatal("SetState accepted a short state")
+	}
+}
+
+func TestStateSpaceDimensions(t *testing.T) {
+	_, err := NewStateSpace(
+		[][]float64{{0, 1}, {0, 0}},
+		[][]float64{{1}},
+		[][]float64{{1, 0}},
+		nil,
+	)
+	if err != ErrDimension {
+		t.Fatalf("err = %v, want ErrDimension", err)
+	}
+}
+
+func TestTransferFunctionMatchesFirstOrder(t *testing.T) {
+	k, tau, dt := 3.0, 0.2, 0.01
+
+	g, err := NewTransferFunction([]float64{k}, []float64{tau, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := g.Discretise(ZOH)
+
+	for i := 1; i <= 100; i++ {
+		y := s.Compute(1, dt)
+		if want := firstOrderStep(k, tau, float64(i)*dt); math.Abs(y-want) > 1e-9 {
+			t.Fatalf("step %d: got %v, want %v", i, y, want)
+		}
+	}
+}
+
+func TestTransferFunctionComposition(t *testing.T) {
+	g1, _ := NewTransferFunction([]float64{2}, []float64{1, 1})
+	g2, _ := NewTransferFunction([]float64{1}, []float64{1, 3})
+	integrator, _ := NewTransferFunction([]float64{5}, []float64{1, 0})
+
+	series, err := g1.Series(g2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := series.DcGain(), 2.0/3.0; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("series dc gain = %v, want %v", got, want)
+	}
+	if got := series.Order(); got != 2 {
+		t.Fatalf("series order = %d, want 2", got)
+	}
+
+	parallel, err := g1.Parallel(g2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parallel.DcGain(), 2.0+1.0/3.0; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("parallel dc gain = %v, want %v", got, want)
+	}
+
+	// the zero value has no denominator and cannot be composed
+	var zero TransferFunction[float64]
+	if _, err := g1.Series(zero); err != ErrZeroDenominator {
+		t.Fatalf("series err = %v, want ErrZeroDenominator", err)
+	}
+	if _, err := zero.Parallel(g2); err != ErrZeroDenominator {
+		t.Fatalf("parallel err = %v, want ErrZeroDenominator", err)
+	}
+
+	// 5/s in unity feedback is 1/(0.2*s + 1)
+	loop, err := integrator.UnityFeedback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := loop.Discretise(ZOH)
+	for i := 1; i <= 100; i++ {
+		y := s.Compute(1, 0.01)
+		if want := firstOrderStep(1, 0.2, float64(i)*0.01); math.Abs(y-want) > 1e-9 {
+			t.Fatalf("step %d: got %v, want %v", i, y, want)
+		}
+	}
+
+	// g1 with g2 in the feedback path: 2(s+3) / ((s+1)(s+3) + 2)
+	fb, err := g1.Feedback(g2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fb.DcGain(), 6.0/5.0; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("feedback dc gain = %v, want %v", got, want)
+	}
+
+	// a pure gain of -1 in unity feedback is ill posed
+	neg, _ := NewTransferFunction([]float64{-1}, []float64{1})
+	if _, err := neg.UnityFeedback(); err != ErrZeroDenominator {
+		t.Fatalf("err = %v, want ErrZeroDenominator", err)
+	}
+}
+
+func TestTransferFunctionValidation(t *testing.T) {
+	if _, err := NewTransferFunction([]float64{1, 0, 0}, []float64{1, 1}); err != ErrImproper {
+		t.Fatalf("err = %v, want ErrImproper", err)
+	}
+	if _, err := NewTransferFunction([]float64{1}, []float64{0, 0}); err != ErrZeroDenominator {
+		t.Fatalf("err = %v, want ErrZeroDenominator", err)
+	}
+}
+
+func TestTransferFunctionStateSpace(t *testing.T) {
+	k, wn, zeta, dt := 2.0, 6.0, 0.4, 0.005
+
+	// biproper lead term exercises the feedthrough path
+	g, _ := NewTransferFunction([]float64{k * wn * wn}, []float64{1, 2 * zeta * wn, wn * wn})
+	lead, _ := NewTransferFunction([]float64{1, 1}, []float64{1, 1})
+	gl, err := g.Series(lead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := gl.Discretise(RK4)
+	so := NewSecondOrder(k, wn, zeta).WithIntegrator(RK4)
+
+	for i := range 400 {
+		got := s.Compute(1, dt)
+		want := so.Compute(1, dt)
+		if math.Abs(got-want) > 1e-6 {
+			t.Fatalf("step %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPidDefaultPath(t *testing.T) {
+	kp, ki, kd, dt := 2.0, 0.5, 0.1, 0.01
+	p := NewPid(kp, ki, kd, 1.0)
+
+	var integral, prev float64
+	for i := range 50 {
+		err := math.Sin(float64(i) * 0.1)
+		integral += err * dt
+		want := kp*err + ki*integral + kd*(err-prev)/dt
+		prev = err
+
+		if got := p.Compute(err, dt); math.Abs(got-want) > 1e-12 {
+			t.Fatalf("step %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPidDerivativeOnMeasurement(t *testing.T) {
+	dt := 0.01
+	p := NewPid(1.0, 0.0, 1.0, 1.0)
+	p.SetpointWeights(1, 0)
+
+	p.ComputeSp(0, 0, dt)
+	if got := p.ComputeSp(1, 0, dt); got != 1 {
+		t.Fatalf("setpoint step gave %v, want proportional term only", got)
+	}
+
+	q := NewPid(1.0, 0.0, 1.0, 1.0)
+	q.ComputeSp(0, 0, dt)
+	if got := q.ComputeSp(1, 0, dt); got != 1+1/dt {
+		t.Fatalf("error derivative gave %v, want %v", got, 1+1/dt)
+	}
+}
+
+func TestPidOutputLimitsAndWindup(t *testing.T) {
+	dt := 0.01
+
+	run := func(p *Pid[float64]) (float64, int) {
+		plant := NewFirstOrder(1.0, 0.5).WithIntegrator(ZOH)
+		var y, peak float64
+		settled := -1
+		for i := range 3000 {
+			sp := 0.8
+			u := p.ComputeSp(sp, y, dt)
+			if u < -1 || u > 1 {
+				t.Fatalf("output %v outside limits", u)
+			}
+			y = plant.Compute(u, dt)
+			peak = max(peak, y)
+			if math.Abs(y-sp) > 0.02 {
+				settled = -1
+			} else if settled < 0 {
+				settled = i
+			}
+		}
+		return peak, settled
+	}
+
+	newPid := func() *Pid[float64] {
+		p := NewPid(3.0, 6.0, 0.0, 1.0)
+		p.OutputLimits(-1, 1)
+		return p
+	}
+
+	plain := newPid()
+	peakPlain, _ := run(plain)
+
+	back := newPid()
+	back.BackCalculation(2)
+	peakBack, settledBack := run(back)
+
+	cond := newPid()
+	cond.ConditionalIntegration(true)
+	peakCond, settledCond := run(cond)
+
+	if peakBack >= peakPlain || peakCond >= peakPlain {
+		t.Fatalf("anti windup did not reduce overshoot: plain %v, back %v, cond %v", peakPlain, peakBack, peakCond)
+	}
+	if settledBack < 0 || settledCond < 0 {
+		t.Fatal("anti windup loops did not settle")
+	}
+}
+
+func TestPidBumpless(t *testing.T) {
+	dt := 0.01
+	p := NewPid(2.0, 1.0, 0.2, 0.5)
+
+	var u float64
+	for i := range 100 {
+		u = p.ComputeSp(1, float64(i)*0.005, dt)
+	}
+
+	p.SetGains(4.0, 3.0, 0.1)
+	after := p.kp*p.lastP + p.ki*p.integral + p.kd*p.derivative
+	if math.Abs(after-u) > 1e-12 {
+		t.Fatalf("gain change bumped output from %v to %v", u, after)
+	}
+
+	p.SetManual(0.3)
+	for range 10 {
+		if got := p.ComputeSp(1, 0.5, dt); got != 0.3 {
+			t.Fatalf("manual output = %v, want 0.3", got)
+		}
+	}
+
+	p.SetAuto()
+	if got := p.ComputeSp(1, 0.5, dt); math.Abs(got-0.3) > 0.05 {
+		t.Fatalf("auto transfer bumped output to %v", got)
+	}
+}
+
+func TestPidGainChange(t *testing.T) {
+	dt := 0.01
+	p := NewPid(2.0, 4.0, 0.05, 0.5)
+	p.OutputLimits(-3, 3)
+	p.AntiWindup(-5, 5)
+	plant := NewFirstOrder(1.0, 0.5)
+
+	// at steady state the integral carries the whole output, so dropping ki would step it
+	gains := map[int][3]float64{
+		500:  {3, 0, 0.1},
+		800:  {1, 0, 0},
+		1100: {2, 6, 0.05},
+	}
+
+	var y, u float64
+	for i := range 1400 {
+		sp := 1.0
+		if i >= 700 {
+			sp = 1.5
+		}
+
+		g, switched := gains[i]
+		if switched {
+			p.SetGains(g[0], g[1], g[2])
+			if got := p.output(); math.Abs(got-u) > 1e-12 {
+				t.Fatalf("step %d: gain change moved the output from %v to %v", i, u, got)
+			}
+		}
+
+		next := p.ComputeSp(sp, y, dt)
+		if switched && math.Abs(next-u) > 0.05 {
+			t.Fatalf("step %d: output jumped from %v to %v", i, u, next)
+		}
+		u = next
+		y = plant.Compute(u, dt)
+	}
+	if math.Abs(y-1.5) > 0.05 {
+		t.Fatalf("loop did not settle after the gain changes, y = %v", y)
+	}
+}
+
+func TestRelayAutotune(t *testing.T) {
+	// 1/(s+1)^3 has ku = 8 and pu = 2*pi/sqrt(3)
+	g, _ := NewTransferFunction([]float64{1}, []float64{1, 3, 3, 1})
+	plant := g.Discretise(ZOH)
+
+	res, err := RelayTest(plant, RelayConfig[float64]{Setpoint: 0, Amplitude: 1, Cycles: 4}, 0.001, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(res.Ku-8)/8 > 0.1 {
+		t.Fatalf("ku = %v, want about 8", res.Ku)
+	}
+	if pu := 2 * math.Pi / math.Sqrt(3); math.Abs(res.Pu-pu)/pu > 0.05 {
+		t.Fatalf("pu = %v, want about %v", res.Pu, pu)
+	}
+
+	kp, ki, kd := res.Gains(ZieglerNicholsPID)
+	if math.Abs(kp-0.6*res.Ku) > 1e-12 || math.Abs(ki-1.2*res.Ku/res.Pu) > 1e-12 || math.Abs(kd-0.075*res.Ku*res.Pu) > 1e-12 {
+		t.Fatalf("unexpected ziegler nichols gains %v %v %v", kp, ki, kd)
+	}
+
+	// the oscillation cannot settle within a fraction of a period
+	if _, err := RelayTest(g.Discretise(ZOH), RelayConfig[float64]{Amplitude: 1}, 0.001, 1); err != ErrNoOscillation {
+		t.Fatalf("err = %v, want ErrNoOscillation", err)
+	}
+}
+
+func TestFopdtIdentification(t *testing.T) {
+	k, tau, dt := 2.0, 0.8, 0.001
+
+	m, err := IdentifyFopdt(NewFirstOrder(k, tau).WithIntegrator(ZOH), 0.5, dt, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.K-k) > 1e-3 || math.Abs(m.Tau-tau) > 1e-2 || m.Theta > 1e-2 {
+		t.Fatalf("fit = %+v, want k=%v tau=%v theta=0", m, k, tau)
+	}
+
+	kp, ki, kd := Fopdt[float64]{K: 2, Tau: 4, Theta: 1}.Simc(1)
+	if math.Abs(kp-1) > 1e-12 || math.Abs(ki-0.25) > 1e-12 || kd != 0 {
+		t.Fatalf("simc gains = %v %v %v, want 1 0.25 0", kp, ki, kd)
+	}
+
+	// check the ultimate point against the phase condition
+	ku, pu := Fopdt[float64]{K: 1, Tau: 1, Theta: 0.5}.Ultimate()
+	w := 2 * math.Pi / pu
+	if phase := math.Atan(w) + w*0.5; math.Abs(phase-math.Pi) > 1e-9 {
+		t.Fatalf("phase at crossover = %v, want pi", phase)
+	}
+	if math.Abs(ku-math.Sqrt(1+w*w)) > 1e-9 {
+		t.Fatalf("ku = %v, want %v", ku, math.Sqrt(1+w*w))
+	}
+}
+
+func TestFrequencyResponse(t *testing.T) {
+	k, wn, zeta := 2.0, 5.0, 0.2
+	so := NewSecondOrder(k, wn, zeta)
+	g, _ := NewTransferFunction([]float64{k * wn * wn}, []float64{1, 2 * zeta * wn, wn * wn})
+
+	// at the natural frequency the magnitude is k/(2*zeta) with -90 degrees of phase
+	for name, sys := range map[string]FrequencySystem{
+		"second_order":      so,
+		"transfer_function": g,
+		"state_space":       g.StateSpace(),
+	} {
+		p := Bode(sys, []float64{0.01, wn})[1]
+		if math.Abs(p.Mag-k/(2*zeta)) > 1e-9 || math.Abs(p.Phase+90) > 1e-9 {
+			t.Fatalf("%s: mag %v phase %v at wn", name, p.Mag, p.Phase)
+		}
+	}
+
+	points := Bode(so, LogSpace(0.1, 100, 50))
+	if len(points) != 50 || points[49].Phase > -170 {
+		t.Fatalf("unexpected bode tail %+v", points[len(points)-1])
+	}
+	cols := BodeColumns(points)
+	if len(cols) != 6 || len(cols["phase"]) != 50 {
+		t.Fatalf("unexpected csv columns %d", len(cols))
+	}
+}
+
+func TestStabilityMargins(t *testing.T) {
+	// k/(tau*s + 1) crosses unity gain at sqrt(k^2 - 1)/tau and never reaches -180 degrees
+	k, tau := 10.0, 0.5
+	m := StabilityMargins(NewFirstOrder(k, tau), 1e-3, 1e3, 400)
+
+	wgc := math.Sqrt(k*k-1) / tau
+	if math.Abs(m.GainCrossover-wgc) > 1e-6 {
+		t.Fatalf("gain crossover = %v, want %v", m.GainCrossover, wgc)
+	}
+	if pm := 180 - math.Atan(wgc*tau)*180/math.Pi; math.Abs(m.PhaseMargin-pm) > 1e-6 {
+		t.Fatalf("phase margin = %v, want %v", m.PhaseMargin, pm)
+	}
+	if !math.IsInf(m.GainMargin, 1) {
+		t.Fatalf("gain margin = %v, want +inf", m.GainMargin)
+	}
+
+	// 2/(s+1)^3 reaches -180 degrees at sqrt(3) rad/s with |g| = 1/4
+	g, _ := NewTransferFunction([]float64{2}, []float64{1, 3, 3, 1})
+	m = StabilityMargins(g, 1e-3, 1e3, 400)
+	if math.Abs(m.PhaseCrossover-math.Sqrt(3)) > 1e-6 || math.Abs(m.GainMargin-4) > 1e-6 {
+		t.Fatalf("phase crossover %v with gain margin %v, want sqrt(3) and 4", m.PhaseCrossover, m.GainMargin)
+	}
+	if math.Abs(m.GainMarginDb()-20*math.Log10(4)) > 1e-6 {
+		t.Fatalf("gain margin db = %v", m.GainMarginDb())
+	}
+
+	// the lead loop 2s/(s+1) crosses unity gain at 1/sqrt(3) with +60 degrees of phase
+	lead, _ := NewTransferFunction([]float64{2, 0}, []float64{1, 1})
+	m = StabilityMargins(lead, 1e-3, 1e3, 400)
+	if math.Abs(m.GainCrossover-1/math.Sqrt(3)) > 1e-6 || math.Abs(m.PhaseMargin-240) > 1e-6 {
+		t.Fatalf("lead crossover %v with phase margin %v, want 1/sqrt(3) and 240", m.GainCrossover, m.PhaseMargin)
+	}
+
+	if bw := Bandwidth(NewFirstOrder(3.0, 0.25), 1e-3, 1e3, 200); math.Abs(bw-4) > 1e-6 {
+		t.Fatalf("bandwidth = %v, want 4", bw)
+	}
+}
+
+func TestStepMetricsSecondOrder(t *testing.T) {
+	// proportional control of wn^2/(s*(s + 2*zeta*wn)) closes to the standard second order loop
+	wn, zeta, dt := 4.0, 0.3, 1e-4
+
+	g, _ := NewTransferFunction([]float64{wn * wn}, []float64{1, 2 * zeta * wn, 0})
+	_, m := SimulateStep(NewPid(1.0, 0, 0, 1), g.Discretise(ZOH), NewStep(1.0, 0), 10, dt, 0.02)
+
+	wd := wn * math.Sqrt(1-zeta*zeta)
+	if want := 100 * math.Exp(-zeta*math.Pi/math.Sqrt(1-zeta*zeta)); math.Abs(m.Overshoot-want) > 0.1 {
+		t.Fatalf("overshoot = %v, want %v", m.Overshoot, want)
+	}
+	if want := math.Pi / wd; math.Abs(m.PeakTime-want) > 1e-3 {
+		t.Fatalf("peak time = %v, want %v", m.PeakTime, want)
+	}
+
+	// sample the analytic response densely for rise and settling times
+	var t10, t90, ts float64
+	for i := 1; i <= 100000; i++ {
+		tt := float64(i) * dt
+		y := secondOrderStep(1, wn, zeta, tt)
+		if t10 == 0 && y >= 0.1 {
+			t10 = tt
+		}
+		if t90 == 0 && y >= 0.9 {
+			t90 = tt
+		}
+		if math.Abs(y-1) > 0.02 {
+			ts = tt + dt
+		}
+	}
+	if math.Abs(m.RiseTime-(t90-t10)) > 2e-3 {
+		t.Fatalf("rise time = %v, want %v", m.RiseTime, t90-t10)
+	}
+	if math.Abs(m.SettlingTime-ts) > 2e-2 {
+		t.Fatalf("settling time = %v, want %v", m.SettlingTime, ts)
+	}
+	if math.Abs(m.SteadyStateError) > 1e-4 {
+		t.Fatalf("steady state error = %v", m.SteadyStateError)
+	}
+
+	// iae of the underdamped response is bounded below by ise for errors under 1
+	if m.IAE <= 0 || m.ISE <= 0 || m.ITAE <= 0 || m.ISE > m.IAE {
+		t.Fatalf("unexpected error integrals %v %v %v", m.IAE, m.ISE, m.ITAE)
+	}
+}
+
+func TestStepMetricsFirstOrder(t *testing.T) {
+	// open loop data: iae of 1 - e^(-t/tau) over a long horizon is tau
+	tau, dt := 0.5, 1e-4
+	s := NewFirstOrder(1.0, tau).WithIntegrator(ZOH)
+
+	n := 100000
+	ts := make([]float64, n)
+	ys := make([]float64, n)
+	for i := 1; i < n; i++ {
+		ts[i] = float64(i) * dt
+		ys[i] = s.Compute(1, dt)
+	}
+
+	m := StepResponse(ts, ys, 0, 1, 0.02)
+	if math.Abs(m.IAE-tau) > 1e-3 || math.Abs(m.ITAE-tau*tau) > 1e-3 {
+		t.Fatalf("iae = %v, itae = %v, want %v and %v", m.IAE, m.ITAE, tau, tau*tau)
+	}
+	if want := tau * math.Log(9); math.Abs(m.RiseTime-want) > 1e-3 {
+		t.Fatalf("rise time = %v, want %v", m.RiseTime, want)
+	}
+	if m.Overshoot != 0 {
+		t.Fatalf("overshoot = %v, want 0", m.Overshoot)
+	}
+}
+
+func TestSignalCombinators(t *testing.T) {
+	step := NewStep(2.0, 1)
+	ramp := NewRamp(1.0, 0)
+
+	var _ Signal[float64] = NewSine(1.0, 1, 0, 0)
+	var _ Signal[int] = NewStep(1, 0)
+
+	sum := NewSum[float64](step, ramp, NewConstant(0.5))
+	if got := sum.Compute(2); got != 4.5 {
+		t.Fatalf("sum = %v, want 4.5", got)
+	}
+
+	prod := NewProduct[float64](step, ramp)
+	if got := prod.Compute(3); got != 6 {
+		t.Fatalf("product = %v, want 6", got)
+	}
+
+	if got := NewScale[float64](ramp, 2, 1).Compute(3); got != 7 {
+		t.Fatalf("scale = %v, want 7", got)
+	}
+	if got := NewShift[float64](ramp, 1).Compute(3); got != 2 {
+		t.Fatalf("shift = %v, want 2", got)
+	}
+	if got := NewClip[float64](ramp, 5, 0).Compute(8); got != 5 {
+		t.Fatalf("clip = %v, want 5", got)
+	}
+
+	seq := NewSequence(
+		Segment[float64]{Signal: NewConstant(1.0), Duration: 2},
+		Segment[float64]{Signal: ramp, Duration: 3},
+	)
+	for _, tt := range []struct{ t, want float64 }{{-1, 1}, {1, 1}, {2, 0}, {4, 2}, {7, 5}} {
+		if got := seq.Compute(tt.t); got != tt.want {
+			t.Fatalf("sequence(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+
+	rep := NewRepeat[float64](ramp, 2)
+	if got := rep.Compute(5.5); math.Abs(got-1.5) > 1e-12 {
+		t.Fatalf("repeat = %v, want 1.5", got)
+	}
+	if got := rep.Compute(-0.5); math.Abs(got-1.5) > 1e-12 {
+		t.Fatalf("repeat before zero = %v, want 1.5", got)
+	}
+}
+
+func TestSignalGenerators(t *testing.T) {
+	saw := NewSawtooth(1.0, 2, 0, 0)
+	if got := saw.Compute(0.25); math.Abs(got) > 1e-12 {
+		t.Fatalf("sawtooth mid period = %v, want 0", got)
+	}
+
+	pwm := NewPwm(5.0, 10, 0.25, 1)
+	if pwm.Compute(0.01) != 6 || pwm.Compute(0.05) != 1 {
+		t.Fatalf("pwm levels %v %v", pwm.Compute(0.01), pwm.Compute(0.05))
+	}
+
+	// the phase of a linear chirp is 2*pi*(f0*t + (f1 - f0)*t^2/(2*duration))
+	ch := NewChirp(1.0, 1, 11, 10)
+	if got, want := ch.Compute(3), math.Sin(2*math.Pi*(3+0.5*9)); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("chirp = %v, want %v", got, want)
+	}
+	if got, want := ch.Compute(12), math.Sin(2*math.Pi*(60+11*2)); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("chirp after sweep = %v, want %v", got, want)
+	}
+	lc := NewLogChirp(1.0, 1, 100, 2)
+	if math.Abs(lc.Compute(0)) > 1e-12 {
+		t.Fatalf("log chirp at zero = %v", lc.Compute(0))
+	}
+
+	for order := 2; order <= 16; order++ {
+		p := NewPrbs(1.0, 0.1, order, 1)
+		if p.Length() != 1<<order-1 {
+			t.Fatalf("order %d length %d", order, p.Length())
+		}
+
+		// a maximal length sequence has one more high bit than low bits
+		var sum float64
+		for k := range p.Length() {
+			sum += p.Compute(float64(k)*0.1 + 0.05)
+		}
+		if sum != 1 {
+			t.Fatalf("order %d is not maximal length, balance %v", order, sum)
+		}
+	}
+
+	wn := NewWhiteNoise(2.0, 0.01, 42)
+	var mean, sq float64
+	n := 20000
+	for k := range n {
+		v := wn.Compute(float64(k)*0.01 + 0.005)
+		mean += v
+		sq += v * v
+	}
+	mean /= float64(n)
+	if std := math.Sqrt(sq/float64(n) - mean*mean); math.Abs(mean) > 0.05 || math.Abs(std-2) > 0.05 {
+		t.Fatalf("white noise mean %v std %v", mean, std)
+	}
+	if wn.Compute(3.2) != NewWhiteNoise(2.0, 0.01, 42).Compute(3.2) {
+		t.Fatal("white noise is not reproducible")
+	}
+
+	cn := NewColoredNoise(1.0, 0.5, 0.01, 7)
+	late := cn.Compute(50)
+	if early := cn.Compute(1); early != NewColoredNoise(1.0, 0.5, 0.01, 7).Compute(1) || late != cn.Compute(50) {
+		t.Fatal("colored noise is not reproducible")
+	}
+}
+
+func TestMotionProfiles(t *testing.T) {
+	tr := NewTrapezoidal(1.0, 5.0, 2, 4, 0.5)
+	// ta = 0.5, tv = 1.5, total 2.5 after the delay
+	for _, tt := range []struct{ t, want float64 }{{0, 1}, {0.5, 1}, {1, 1.5}, {2.5, 4.5}, {3, 5}, {10, 5}} {
+		if got := tr.Compute(tt.t); math.Abs(got-tt.want) > 1e-12 {
+			t.Fatalf("trapezoidal(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+
+	short := NewTrapezoidal(0.0, -1.0, 10, 4, 0)
+	if got := short.Compute(0.5); math.Abs(got+0.5) > 1e-12 {
+		t.Fatalf("triangular midpoint = %v, want -0.5", got)
+	}
+
+	for _, d := range []float64{10, 0.5, 0.01} {
+		sc := NewSCurve(0.0, d, 2, 4, 20, 0)
+
+		// check the limits by finite differences
+		dt := 1e-4
+		prev, prevV := 0.0, 0.0
+		for i := 1; i < 200000; i++ {
+			p := sc.Compute(float64(i) * dt)
+			v := (p - prev) / dt
+			if v > 2+1e-6 || math.Abs(v-prevV)/dt > 4+1e-2 {
+				t.Fatalf("distance %v: limits violated at %v", d, float64(i)*dt)
+			}
+			prev, prevV = p, v
+		}
+		if math.Abs(prev-d) > 1e-12 {
+			t.Fatalf("distance %v: ended at %v", d, prev)
+		}
+		if mid := sc.Compute(sc.prof.end / 2); math.Abs(mid-d/2) > 1e-9 {
+			t.Fatalf("distance %v: midpoint %v", d, mid)
+		}
+	}
+}
+
+func TestProfileDerivatives(t *testing.T) {
+	sc := NewSCurve(2.0, -3.0, 1.5, 2, 8, 0)
+
+	if got := sc.Duration(); got <= 0 {
+		t.Fatalf("duration = %v", got)
+	}
+
+	// velocity and acceleration agree with finite differences of position
+	h := 1e-6
+	for _, tt := range []float64{0.1, 0.4, 1.0, 2.0, sc.Duration() - 0.1} {
+		p, v, a := sc.At(tt)
+		if math.Abs(p-sc.Compute(tt)) > 1e-12 {
+			t.Fatalf("At(%v) position mismatch", tt)
+		}
+		pm, vm, _ := sc.At(tt - h)
+		pp, vp, _ := sc.At(tt + h)
+		if math.Abs((pp-pm)/(2*h)-v) > 1e-5 || math.Abs((vp-vm)/(2*h)-a) > 1e-5 {
+			t.Fatalf("At(%v) derivatives mismatch", tt)
+		}
+	}
+
+	tr := NewTrapezoidal(0.0, 1.0, 1, 1, 0)
+	if got := tr.Duration(); math.Abs(got-2) > 1e-12 {
+		t.Fatalf("triangular duration = %v, want 2", got)
+	}
+	if _, v, a := tr.At(0.5); v != 0.5 || a != 1 {
+		t.Fatalf("At(0.5) = %v %v, want 0.5 1", v, a)
+	}
+}
+
+func TestOnlineTrajectory(t *testing.T) {
+	vmax, amax, jmax, dt := 2.0, 4.0, 20.0, 0.001
+
+	for _, j := range []float64{jmax, 0} {
+		tr := NewOnlineTrajectory(0.0, vmax, amax, j)
+
+		goal := 5.0
+		prevA := 0.0
+		steps := 0
+		for i := range 20000 {
+			if i == 1000 {
+				// retarget mid motion, reversing direction
+				goal = -1.0
+			}
+
+			tr.Compute(goal, dt)
+			steps = i
+
+			if math.Abs(tr.Vel()) > vmax+1e-9 || math.Abs(tr.Acc()) > amax+1e-9 {
+				t.Fatalf("jerk %v: limits violated at step %d: v=%v a=%v", j, i, tr.Vel(), tr.Acc())
+			}
+			if j > 0 && math.Abs(tr.Acc()-prevA) > jmax*dt+1e-9 {
+				t.Fatalf("jerk limit violated at step %d", i)
+			}
+			prevA = tr.Acc()
+
+			if i > 1000 && tr.Done(goal) {
+				break
+			}
+		}
+
+		if !tr.Done(goal) {
+			t.Fatalf("jerk %v: did not reach goal, at %v", j, tr.Pos())
+		}
+
+		// the move should stay close to the offline time optimum from the retarget point
+		if float64(steps)*dt > 6 {
+			t.Fatalf("jerk %v: took %v s", j, float64(steps)*dt)
+		}
+	}
+
+	// a fresh rest to rest move is close to the offline s-curve
+	tr := NewOnlineTrajectory(0.0, vmax, amax, jmax)
+	sc := NewSCurve(0.0, 3.0, vmax, amax, jmax, 0)
+	n := 0
+	for !tr.Done(3) && n < 100000 {
+		tr.Compute(3, dt)
+		n++
+	}
+	if got, want := float64(n)*dt, sc.Duration(); got > want*1.1 {
+		t.Fatalf("online move took %v s, offline %v s", got, want)
+	}
+}
+
+func TestDlqr(t *testing.T) {
+	// scalar integrator, p solves p^2 - p - 1 = 0
+	k, err := Dlqr([][]float64{{1}}, [][]float64{{1}}, [][]float64{{1}}, [][]float64{{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := (1 + math.Sqrt(5)) / 2
+	if want := p / (1 + p); math.Abs(k[0][0]-want) > 1e-9 {
+		t.Fatalf("k = %v, want %v", k[0][0], want)
+	}
+
+	// regulate a double integrator with the designed gain
+	ss, _ := NewStateSpace([][]float64{{0, 1}, {0, 0}}, [][]float64{{0}, {1}}, [][]float64{{1, 0}}, nil)
+	ss.WithIntegrator(ZOH)
+
+	dt := 0.05
+	ad, bd := ss.Discrete(dt)
+	k, err = Dlqr(ad, bd, [][]float64{{10, 0}, {0, 1}}, [][]float64{{0.1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewStateFeedback(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss.SetState([]float64{1, 0})
+	for range 400 {
+		ss.Compute(sf.Compute(ss.State(), nil), dt)
+	}
+	for _, xi := range ss.State() {
+		if math.Abs(xi) > 1e-6 {
+			t.Fatalf("state not regulated: %v", ss.State())
+		}
+	}
+
+	// a non stabilisable system never converges
+	_, err = Dlqr([][]float64{{2}}, [][]float64{{0}}, [][]float64{{1}}, [][]float64{{1}})
+	if err != ErrNotConverged {
+		t.Fatalf("expected ErrNotConverged, got %v", err)
+	}
+
+	if _, err = Dlqr(ad, bd, [][]float64{{1}}, [][]float64{{1}}); err != ErrDimension {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+}
+
+func TestAcker(t *testing.T) {
+	a := [][]float64{{0, 1}, {0, 0}}
+	b := [][]float64{{0}, {1}}
+
+	tests := []struct {
+		poles []complex128
+		want  []float64
+	}{
+		{[]complex128{-2, -3}, []float64{6, 5}},
+		{[]complex128{complex(-1, 1), complex(-1, -1)}, []float64{2, 2}},
+	}
+
+	for _, tt := range tests {
+		k, err := Acker(a, b, tt.poles)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.want {
+			if math.Abs(k[0][i]-want) > 1e-9 {
+				t.Fatalf("poles %v: k = %v, want %v", tt.poles, k[0], tt.want)
+			}
+		}
+	}
+
+	if _, err := Acker(a, b, []complex128{complex(-1, 1), -2}); err != ErrPoles {
+		t.Fatalf("expected ErrPoles, got %v", err)
+	}
+	if _, err := Acker(a, b, []complex128{-1}); err != ErrPoles {
+		t.Fatalf("expected ErrPoles, got %v", err)
+	}
+	if _, err := Acker(a, [][]float64{{1}, {0}}, []complex128{-1, -2}); err != ErrUncontrollable {
+		t.Fatalf("expected ErrUncontrollable, got %v", err)
+	}
+}
+
+func TestStateFeedback(t *testing.T) {
+	sf, err := NewStateFeedback([][]float64{{2, 1}, {0, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := sf.Compute([]float64{1, 1}, []float64{2, 3})
+	if u[0] != 4 || u[1] != 6 {
+		t.Fatalf("u = %v", u)
+	}
+
+	sf.OutputLimits(-5, 5)
+	u = sf.Compute([]float64{1, 1}, []float64{2, 3})
+	if u[0] != 4 || u[1] != 5 {
+		t.Fatalf("limited u = %v", u)
+	}
+
+	u = sf.Compute([]float64{1}, nil)
+	if u[0] != 0 || u[1] != 0 {
+		t.Fatalf("expected zeros on bad length, got %v", u)
+	}
+
+	if err := sf.SetGain([][]float64{{1, 2}}); err != ErrDimension {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+}
+
+func TestMpc(t *testing.T) {
+	newPlant := func() *StateSpace[float64] {
+		ss, _ := NewStateSpace([][]float64{{0, 1}, {0, 0}}, [][]float64{{0}, {1}}, [][]float64{{1, 0}}, nil)
+		return ss.WithIntegrator(ZOH)
+	}
+
+	dt := 0.1
+	q := [][]float64{{1, 0}, {0, 0.1}}
+	r := [][]float64{{0.5}}
+
+	// without constraints a long horizon matches the infinite horizon lqr
+	ss := newPlant()
+	ad, bd := ss.Discrete(dt)
+	k, err := Dlqr(ad, bd, q, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mpc, err := NewMpc(ss, dt, 80, q, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := []float64{1, -0.5}
+	u := mpc.Compute(x, nil)[0]
+	if want := -(k[0][0]*x[0] + k[0][1]*x[1]); math.Abs(u-want) > 1e-4 {
+		t.Fatalf("unconstrained mpc u = %v, lqr u = %v", u, want)
+	}
+
+	// constrained regulation towards a reference state
+	ss = newPlant()
+	mpc, _ = NewMpc(ss, dt, 20, q, r)
+	if err := mpc.InputLimits([]float64{-1}, []float64{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mpc.RateLimits([]float64{-2}, []float64{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	ref := []float64{3, 0}
+	prev := 0.0
+	for i := range 300 {
+		u := mpc.Compute(ss.State(), ref)[0]
+		if !mpc.Converged() {
+			t.Fatalf("qp did not converge at step %d", i)
+		}
+		if u > 1+1e-9 || u < -1-1e-9 {
+			t.Fatalf("input limit violated at step %d: %v", i, u)
+		}
+		if math.Abs(u-prev) > 2*dt+1e-9 {
+			t.Fatalf("rate limit violated at step %d: %v -> %v", i, prev, u)
+		}
+		prev = u
+		ss.Compute([]float64{u}, dt)
+	}
+	if s := ss.State(); math.Abs(s[0]-3) > 1e-3 || math.Abs(s[1]) > 1e-3 {
+		t.Fatalf("state did not reach the reference: %v", s)
+	}
+	if plan := mpc.Plan(); len(plan) != 20 || len(plan[0]) != 1 {
+		t.Fatalf("unexpected plan shape")
+	}
+
+	if _, err := NewMpc(ss, 0, 10, q, r); err != ErrHorizon {
+		t.Fatalf("expected ErrHorizon, got %v", err)
+	}
+	if _, err := NewMpc(ss, dt, 10, r, r); err != ErrDimension {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := mpc.InputLimits([]float64{1, 2}, []float64{3, 4}); err != ErrDimension {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+}
+
+// count how often and with which timestep a controller runs
+type countingController struct {
+	inner Controller[float64]
+	calls int
+	dt    float64
+}
+
+func (c *countingController) Compute(err, dt float64) float64 {
+	c.calls++
+	c.dt = dt
+	return c.inner.Compute(err, dt)
+}
+
+func TestCascade(t *testing.T) {
+	// position loop around a velocity loop around a first order velocity plant
+	outer := &countingController{inner: NewPid(4.0, 0, 0, 0)}
+	inner := NewPid(2.0, 20, 0, 0)
+
+	casc, err := NewCascade(
+		CascadeStage[float64]{Controller: outer, Min: -1, Max: 1, Ratio: 10},
+		CascadeStage[float64]{Controller: inner, Min: -5, Max: 5},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plant := NewFirstOrder(1.0, 0.05)
+	dt := 0.001
+	var pos, vel float64
+	for range 8000 {
+		u := casc.Compute(2, []float64{pos, vel}, dt)
+		if math.Abs(u) > 5 {
+			t.Fatalf("inner limit violated: %v", u)
+		}
+		if v := casc.Outputs()[0]; math.Abs(v) > 1 {
+			t.Fatalf("outer limit violated: %v", v)
+		}
+		vel = plant.Compute(u, dt)
+		pos += vel * dt
+	}
+
+	if outer.calls != 800 || math.Abs(outer.dt-10*dt) > 1e-12 {
+		t.Fatalf("outer stage ran %d times with dt %v", outer.calls, outer.dt)
+	}
+	if math.Abs(pos-2) > 1e-3 {
+		t.Fatalf("position did not settle: %v", pos)
+	}
+
+	casc.Reset()
+	if casc.Outputs()[1] != 0 {
+		t.Fatalf("reset did not clear the outputs")
+	}
+
+	if _, err := NewCascade[float64](); err != ErrCascade {
+		t.Fatalf("expected ErrCascade, got %v", err)
+	}
+	if _, err := NewCascade(CascadeStage[float64]{}); err != ErrCascade {
+		t.Fatalf("expected ErrCascade, got %v", err)
+	}
+}
+
+func TestCascadeSaturationRecovery(t *testing.T) {
+	// a pi stage held in saturation against a stuck measurement must leave the limit
+	// as soon as the error changes sign
+	pi := NewPid(1.0, 5, 0, 0)
+	casc, err := NewCascade(CascadeStage[float64]{Controller: pi, Min: -1, Max: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dt := 0.01
+	for range 500 {
+		if u := casc.Compute(10, []float64{0}, dt); u != 1 {
+			t.Fatalf("output = %v, want the upper limit", u)
+		}
+	}
+
+	if u := casc.Compute(-0.5, []float64{0}, dt); u >= 0 {
+		t.Fatalf("output = %v after the error reversed, integral wound up", u)
+	}
+}
+
+func TestFeedforward(t *testing.T) {
+	st := NewStaticFeedforward(2.0, 0.5)
+	if got := st.Compute(3, 0.1); got != 6.5 {
+		t.Fatalf("static feedforward = %v", got)
+	}
+
+	d := NewDerivativeFeedforward(1.0)
+	if got := d.Compute(1, 0.1); got != 0 {
+		t.Fatalf("first derivative feedforward = %v", got)
+	}
+	if got := d.Compute(1.5, 0.1); math.Abs(got-5) > 1e-12 {
+		t.Fatalf("derivative feedforward = %v", got)
+	}
+
+	// feedforward of the plant inverse removes the steady state error of a p controller
+	plant := NewFirstOrder(2.0, 0.1)
+	casc, _ := NewCascade(CascadeStage[float64]{
+		Controller:  NewPid(1.0, 0, 0, 0),
+		Feedforward: NewStaticFeedforward(0.5, 0),
+	})
+
+	var y float64
+	for range 5000 {
+		y = plant.Compute(casc.Compute(1, []float64{y}, 0.001), 0.001)
+	}
+	if math.Abs(y-1) > 1e-6 {
+		t.Fatalf("feedforward loop settled at %v", y)
+	}
+}
+
+func TestRunnerSimClock(t *testing.T) {
+	clk := NewSimClock(time.Unix(0, 0))
+	period := 10 * time.Millisecond
+
+	var dts []float64
+	r := NewRunner(period, func(dt float64) error {
+		dts = append(dts, dt)
+		if len(dts) == 5 {
+			// miss two deadlines
+			clk.Advance(25 * time.Millisecond)
+		} else {
+			clk.Advance(time.Millisecond)
+		}
+		if len(dts) == 20 {
+			return ErrStop
+		}
+		return nil
+	}).WithClock(clk)
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	st := r.Stats()
+	if st.Steps != 20 || st.Overruns != 1 {
+		t.Fatalf("steps %d overruns %d", st.Steps, st.Overruns)
+	}
+	if dts[0] != 0.01 || math.Abs(dts[5]-0.03) > 1e-12 || dts[6] != 0.01 {
+		t.Fatalf("unexpected timesteps %v", dts[:7])
+	}
+	if st.MaxDt != 30*time.Millisecond || st.MinDt != period || st.MaxJitter != 20*time.Millisecond {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	total := 0
+	for _, n := range st.JitterCounts {
+		total += n
+	}
+	if total != 19 || st.JitterCounts[0] != 18 || st.JitterCounts[len(st.JitterCounts)-1] != 1 {
+		t.Fatalf("unexpected histogram %v", st.JitterCounts)
+	}
+
+	// errors from the step are returned, cancellation stops cleanly
+	boom := errors.New("boom")
+	r = NewRunner(period, func(dt float64) error { return boom }).WithClock(clk)
+	if err := r.Run(context.Background()); err != boom {
+		t.Fatalf("expected step error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r = NewRunner(period, func(dt float64) error {
+		cancel()
+		return nil
+	}).WithClock(clk)
+	if err := r.Run(ctx); err != nil || r.Stats().Steps != 1 {
+		t.Fatalf("cancel: err %v steps %d", err, r.Stats().Steps)
+	}
+}
+
+func TestRunnerSignal(t *testing.T) {
+	l := system.NewSignalListener(syscall.SIGUSR1)
+	defer l.Stop()
+
+	r := NewRunner(time.Millisecond, func(dt float64) error {
+		return nil
+	}).WithSignals(l)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(syscall.SIGUSR1)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("runner did not stop on the signal")
+	}
+	if r.Stats().Steps == 0 {
+		t.Fatalf("runner did not execute any step")
+	}
+}
+
+// record a plant driven by u, sampling the output before each input is applied
+func recordPlant(plant Plant[float64], u []float64, dt float64) (ts, ys []float64) {
+	ts = make([]float64, len(u))
+	ys = make([]float64, len(u))
+	var y float64
+	for k, uk := range u {
+		ts[k] = float64(k) * dt
+		ys[k] = y
+		y = plant.Compute(uk, dt)
+	}
+	return ts, ys
+}
+
+func TestFitArx(t *testing.T) {
+	// y_k = 0.6*y_{k-1} - 0.1*y_{k-2} + 0.5*u_{k-2} + 0.2*u_{k-3}
+	prbs := NewPrbs(1.0, 1, 7, 1)
+	n := 400
+	u := make([]float64, n)
+	y := make([]float64, n)
+	for k := range n {
+		u[k] = prbs.Compute(float64(k))
+		if k >= 2 {
+			y[k] = 0.6*y[k-1] - 0.1*y[k-2] + 0.5*u[k-2]
+		}
+		if k >= 3 {
+			y[k] += 0.2 * u[k-3]
+		}
+	}
+
+	m, q, err := FitArx(u, y, 2, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{-0.6, 0.1, 0.5, 0.2}
+	got := []float64{m.A[0], m.A[1], m.B[0], m.B[1]}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("coefficients %v, want %v", got, want)
+		}
+	}
+	if q.R2 < 1-1e-9 || q.Rmse > 1e-9 || len(q.Residuals) != n {
+		t.Fatalf("unexpected quality %v %v", q.R2, q.Rmse)
+	}
+	if math.Abs(m.DcGain()-0.7/0.5) > 1e-9 {
+		t.Fatalf("dc gain %v", m.DcGain())
+	}
+
+	if _, _, err := FitArx(u[:3], y[:3], 2, 2, 2); err != ErrInsufficientData {
+		t.Fatalf("expected ErrInsufficientData, got %v", err)
+	}
+	if _, _, err := FitArx(make([]float64, n), make([]float64, n), 1, 1, 1); err != ErrInsufficientData {
+		t.Fatalf("expected ErrInsufficientData for a silent input, got %v", err)
+	}
+}
+
+func TestFitFopdt(t *testing.T) {
+	dt := 0.01
+	k, tau, delay := 2.0, 0.5, 15
+
+	// delay the input of a first order plant by a whole number of samples
+	prbs := NewPrbs(1.0, 0.2, 7, 3)
+	plant := NewFirstOrder(k, tau).WithIntegrator(ZOH)
+	n := 2000
+	u := make([]float64, n)
+	delayed := make([]float64, n)
+	for i := range n {
+		u[i] = prbs.Compute(float64(i) * dt)
+		if i >= delay {
+			delayed[i] = u[i-delay]
+		}
+	}
+	ts, ys := recordPlant(plant, delayed, dt)
+
+	m, q, err := FitFopdt(ts, u, ys, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.K-k) > 1e-6 || math.Abs(m.Tau-tau) > 1e-6 || math.Abs(m.Theta-float64(delay)*dt) > 1e-9 {
+		t.Fatalf("fitted %+v", m)
+	}
+	if q.R2 < 0.999999 {
+		t.Fatalf("r2 = %v", q.R2)
+	}
+	if sys := m.System(); sys == nil {
+		t.Fatalf("nil system")
+	}
+}
+
+func TestFitSecondOrder(t *testing.T) {
+	dt := 0.01
+	k, wn, zeta := 1.5, 6.0, 0.3
+
+	prbs := NewPrbs(1.0, 0.1, 8, 5)
+	u := make([]float64, 3000)
+	for i := range u {
+		u[i] = prbs.Compute(float64(i) * dt)
+	}
+	ts, ys := recordPlant(NewSecondOrder(k, wn, zeta).WithIntegrator(ZOH), u, dt)
+
+	m, q, err := FitSecondOrder(ts, u, ys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.K-k) > 1e-6 || math.Abs(m.Wn-wn) > 1e-6 || math.Abs(m.Zeta-zeta) > 1e-6 {
+		t.Fatalf("fitted %+v", m)
+	}
+	if q.R2 < 0.999999 {
+		t.Fatalf("r2 = %v", q.R2)
+	}
+
+	// the identified system reproduces the recording
+	_, sim := recordPlant(m.System().WithIntegrator(ZOH), u, dt)
+	for i := range sim {
+		if math.Abs(sim[i]-ys[i]) > 1e-4 {
+			t.Fatalf("simulated sample %d = %v, recorded %v", i, sim[i], ys[i])
+		}
+	}
+
+	// logged data round trips through csv
+	path := t.TempDir() + "/log.csv"
+	cols := map[string][]any{"t": {}, "u": {}, "y": {}}
+	for i := range ts {
+		cols["t"] = append(cols["t"], ts[i])
+		cols["u"] = append(cols["u"], u[i])
+		cols["y"] = append(cols["y"], ys[i])
+	}
+	if err := csv.Save(cols, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := csv.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, _ := csv.Floats(data["t"])
+	cu, _ := csv.Floats(data["u"])
+	cy, err := csv.Floats(data["y"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, q, err := FitSecondOrder(ct, cu, cy); err != nil || q.R2 < 0.999 {
+		t.Fatalf("fit from csv: %v r2 %v", err, q.R2)
+	}
+}
+
+// chain two plants in series
+type seriesPlant struct {
+	first, second Plant[float64]
+}
+
+func (s seriesPlant) Compute(u, dt float64) float64 {
+	return s.second.Compute(s.first.Compute(u, dt), dt)
+}
+
+func TestDelay(t *testing.T) {
+	// whole sample delay
+	d := NewDelay(0.03)
+	var out []float64
+	for i := range 6 {
+		out = append(out, d.Compute(float64(i+1), 0.01))
+	}
+	want := []float64{0, 0, 0, 1, 2, 3}
+	for i := range want {
+		if math.Abs(out[i]-want[i]) > 1e-12 {
+			t.Fatalf("delayed %v, want %v", out, want)
+		}
+	}
+
+	// fractional delay interpolates a ramp exactly
+	d = NewDelay(0.025)
+	for i := range 20 {
+		y := d.Compute(float64(i), 0.01)
+		if i >= 3 {
+			if want := float64(i) - 2.5; math.Abs(y-want) > 1e-9 {
+				t.Fatalf("step %d: %v, want %v", i, y, want)
+			}
+		}
+	}
+
+	// zero delay passes the input through
+	d = NewDelay(0.0)
+	if y := d.Compute(4, 0.01); y != 4 {
+		t.Fatalf("zero delay output %v", y)
+	}
+
+	d.Reset()
+	if y := d.Compute(0, 0.01); y != 0 {
+		t.Fatalf("reset delay output %v", y)
+	}
+}
+
+func TestSmithPredictor(t *testing.T) {
+	dt, dead := 0.01, 1.0
+
+	newPlant := func() Plant[float64] {
+		return seriesPlant{NewFirstOrder(1.0, 1.0), NewDelay(dead)}
+	}
+	step := NewStep(1.0, 0.0)
+
+	// gains tuned for the delay free plant destabilise the delayed loop
+	_, plain := SimulateStep[float64](NewPid(4.0, 4.0, 0, 0), newPlant(), step, 30, dt, 0.02)
+	if !math.IsInf(float64(plain.SettlingTime), 1) && plain.SettlingTime < 20 {
+		t.Fatalf("plain pid unexpectedly settled in %v s", plain.SettlingTime)
+	}
+
+	smith := NewSmithPredictor(NewPid(4.0, 4.0, 0, 0), NewFirstOrder(1.0, 1.0), dead)
+	_, m := SimulateStep[float64](smith, newPlant(), step, 30, dt, 0.02)
+	if m.Overshoot > 5 || m.SettlingTime > 3 || math.Abs(m.SteadyStateError) > 1e-3 {
+		t.Fatalf("smith predictor response %+v", m)
+	}
+
+	smith.Reset()
+	if u := smith.Compute(0, dt); u != 0 {
+		t.Fatalf("reset predictor output %v", u)
+	}
+}
+
+func TestGainSchedule(t *testing.T) {
+	points := []GainPoint[float64]{
+		{X: 10, Kp: 3, Ki: 1, Kd: 0},
+		{X: 0, Kp: 1, Ki: 1, Kd: 0.5},
+		{X: 20, Kp: 3, Ki: 2, Kd: 0},
+	}
+
+	lin, err := NewGainSchedule(points, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := lin.At(5); p.Kp != 2 || p.Kd != 0.25 || p.X != 5 {
+		t.Fatalf("linear at 5 = %+v", p)
+	}
+	if p := lin.At(-4); p.Kp != 1 || p.Ki != 1 {
+		t.Fatalf("linear below the table = %+v", p)
+	}
+	if p := lin.At(25); p.Ki != 2 {
+		t.Fatalf("linear above the table = %+v", p)
+	}
+
+	// the cubic mode passes through the points and stays within them between
+	cub, _ := NewGainSchedule(points, Cubic)
+	if p := cub.At(10); p.Kp != 3 || p.Ki != 1 {
+		t.Fatalf("cubic at a point = %+v", p)
+	}
+	for x := 0.0; x <= 20; x += 0.1 {
+		p := cub.At(x)
+		if p.Kp < 1-1e-12 || p.Kp > 3+1e-12 || p.Ki < 1-1e-12 || p.Ki > 2+1e-12 {
+			t.Fatalf("cubic overshoot at %v: %+v", x, p)
+		}
+	}
+	if p := cub.At(5); p.Kp <= 2 {
+		t.Fatalf("cubic should bend towards the flat segment, got %v", p.Kp)
+	}
+
+	if _, err := NewGainSchedule([]GainPoint[float64]{{X: 1}, {X: 1}}, Linear); err != ErrSchedule {
+		t.Fatalf("expected ErrSchedule, got %v", err)
+	}
+	if _, err := NewGainSchedule[float64](nil, Linear); err != ErrSchedule {
+		t.Fatalf("expected ErrSchedule, got %v", err)
+	}
+}
+
+func TestGainScheduleLoading(t *testing.T) {
+	dir := t.TempDir()
+
+	js := dir + "/schedule.json"
+	doc := `{"interpolation": "cubic", "points": [{"x": 0, "kp": 1, "ki": 0.5, "kd": 0, "alpha": 0.5}, {"x": 1, "kp": 2, "ki": 1, "kd": 0.1, "alpha": 1}]}`
+	if err := os.WriteFile(js, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := LoadGainScheduleJSON[float64](js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Interpolation() != Cubic || len(g.Points()) != 2 {
+		t.Fatalf("unexpected schedule %v %v", g.Interpolation(), g.Points())
+	}
+	if p := g.At(1); p.Kd != 0.1 || p.Alpha == nil || *p.Alpha != 1 {
+		t.Fatalf("json point = %+v", p)
+	}
+
+	// alpha = 0 is a value, not a missing one
+	if err := os.WriteFile(js, []byte(`{"points": [{"x": 0, "kp": 1, "ki": 1, "kd": 0, "alpha": 0}, {"x": 1, "kp": 2, "ki": 1, "kd": 0, "alpha": 0}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err = LoadGainScheduleJSON[float64](js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp := NewScheduledPid(NewPid(1.0, 1, 0, 0.7), g, 0.5); sp.Pid().Alpha() != 0 {
+		t.Fatalf("scheduled alpha %v, want 0", sp.Pid().Alpha())
+	}
+	if data, _ := json.Marshal(g.At(0.5)); !strings.Contains(string(data), `"alpha":0`) {
+		t.Fatalf("alpha = 0 dropped from json: %s", data)
+	}
+
+	// without alpha the pid keeps its own
+	g, _ = NewGainSchedule([]GainPoint[float64]{{X: 0, Kp: 1}, {X: 1, Kp: 2}}, Linear)
+	if sp := NewScheduledPid(NewPid(1.0, 1, 0, 0.7), g, 0.5); sp.Pid().Alpha() != 0.7 || g.At(0.5).Alpha != nil {
+		t.Fatalf("unscheduled alpha %v", sp.Pid().Alpha())
+	}
+
+	alpha := 0.5
+	if _, err := NewGainSchedule([]GainPoint[float64]{{X: 0, Alpha: &alpha}, {X: 1}}, Linear); !errors.Is(err, ErrSchedule) {
+		t.Fatalf("expected ErrSchedule for alpha on some points, got %v", err)
+	}
+
+	if err := os.WriteFile(js, []byte(`{"interpolation": "quintic"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGainScheduleJSON[float64](js); !errors.Is(err, ErrSchedule) {
+		t.Fatalf("expected ErrSchedule, got %v", err)
+	}
+
+	path := dir + "/schedule.csv"
+	cols := map[string][]any{"x": {0.0, 2.0}, "kp": {1.0, 3.0}, "ki": {0.0, 1.0}, "kd": {0.0, 0.0}}
+	if err := csv.Save(cols, path); err != nil {
+		t.Fatal(err)
+	}
+	g, err = LoadGainScheduleCSV[float64](path, Linear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := g.At(1); p.Kp != 2 || p.Ki != 0.5 {
+		t.Fatalf("csv point = %+v", p)
+	}
+
+	if _, err := GainScheduleFromColumns[float64](map[string][]any{"x": {"1"}}, Linear); !errors.Is(err, ErrSchedule) {
+		t.Fatalf("expected ErrSchedule, got %v", err)
+	}
+	short := map[string][]any{"x": {"0", "1"}, "kp": {"1", "2"}, "ki": {"1"}, "kd": {"0", "0"}}
+	if _, err := GainScheduleFromColumns[float64](short, Linear); !errors.Is(err, ErrSchedule) {
+		t.Fatalf("expected ErrSchedule for a short column, got %v", err)
+	}
+}
+
+func TestScheduledPid(t *testing.T) {
+	g, _ := NewGainSchedule([]GainPoint[float64]{
+		{X: 0, Kp: 1, Ki: 2, Kd: 0},
+		{X: 1, Kp: 4, Ki: 8, Kd: 0},
+	}, Linear)
+
+	sp := NewScheduledPid(NewPid(0.0, 0, 0, 1), g, 0)
+	if kp, ki, _ := sp.Pid().Gains(); kp != 1 || ki != 2 {
+		t.Fatalf("initial gains %v %v", kp, ki)
+	}
+
+	dt := 0.01
+	var u float64
+	for range 100 {
+		u = sp.Compute(0.5, dt)
+	}
+
+	// moving across the table changes the gains without a jump in the output
+	sp.Schedule(0.5)
+	if kp, _, _ := sp.Pid().Gains(); kp != 2.5 {
+		t.Fatalf("scheduled kp %v", kp)
+	}
+	next := sp.Compute(0.5, dt)
+	if math.Abs(next-u) > 0.1 {
+		t.Fatalf("bump in output: %v -> %v", u, next)
+	}
+	if sp.Operating() != 0.5 {
+		t.Fatalf("operating point %v", sp.Operating())
+	}
+
+	// sweeping through a point with ki = 0 keeps the output continuous
+	g, _ = NewGainSchedule([]GainPoint[float64]{
+		{X: 0, Kp: 1, Ki: 2, Kd: 0},
+		{X: 1, Kp: 2, Ki: 0, Kd: 0},
+		{X: 2, Kp: 1, Ki: 3, Kd: 0},
+	}, Linear)
+	sp = NewScheduledPid(NewPid(0.0, 0, 0, 1), g, 0)
+	plant := NewFirstOrder(1.0, 0.5)
+	var y float64
+	for range 500 {
+		u = sp.ComputeSp(1, y, dt)
+		y = plant.Compute(u, dt)
+	}
+	for i := range 401 {
+		sp.Schedule(float64(i) / 200)
+		next := sp.ComputeSp(1, y, dt)
+		if math.Abs(next-u) > 0.02 {
+			t.Fatalf("bump in output at x = %v: %v -> %v", sp.Operating(), u, next)
+		}
+		u = next
+		y = plant.Compute(u, dt)
+	}
+	if _, ki, _ := sp.Pid().Gains(); ki != 3 {
+		t.Fatalf("final ki %v", ki)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	var _ Snapshotter = (*Pid[float64])(nil)
+	var _ Snapshotter = (*FirstOrder[float64])(nil)
+	var _ Snapshotter = (*SecondOrder[float64])(nil)
+
+	dt := 0.01
+
+	pid := NewPid(2.0, 1, 0.1, 0.5)
+	pid.OutputLimits(-1, 1)
+	pid.BackCalculation(0.5)
+	plant := NewSecondOrder(1.0, 5, 0.4).WithIntegrator(Tustin)
+	lag := NewFirstOrder(1.0, 0.2).WithIntegrator(ZOH)
+
+	y := 0.0
+	step := func(pid *Pid[float64], plant *SecondOrder[float64], lag *FirstOrder[float64], y float64) float64 {
+		u := pid.ComputeSp(1, y, dt)
+		return lag.Compute(plant.Compute(u, dt), dt)
+	}
+	for range 50 {
+		y = step(pid, plant, lag, y)
+	}
+
+	pidBin, _ := pid.MarshalBinary()
+	plantJSON, _ := json.Marshal(plant)
+	lagBin, _ := lag.MarshalBinary()
+
+	var pid2 Pid[float64]
+	var plant2 SecondOrder[float64]
+	var lag2 FirstOrder[float64]
+	if err := pid2.UnmarshalBinary(pidBin); err != nil {
+		t.Fatalf("pid: %v", err)
+	}
+	if err := json.Unmarshal(plantJSON, &plant2); err != nil {
+		t.Fatalf("second order: %v", err)
+	}
+	if err := lag2.UnmarshalBinary(lagBin); err != nil {
+		t.Fatalf("first order: %v", err)
+	}
+
+	// both loops continue identically
+	y2 := y
+	for i := range 100 {
+		y = step(pid, plant, lag, y)
+		y2 = step(&pid2, &plant2, &lag2, y2)
+		if y != y2 {
+			t.Fatalf("step %d: %v != %v", i, y, y2)
+		}
+	}
+
+	if err := pid2.UnmarshalBinary(lagBin); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for wrong kind, got %v", err)
+	}
+	if err := lag2.UnmarshalBinary(lagBin[:len(lagBin)-1]); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for truncated data, got %v", err)
+	}
+	if err := lag2.UnmarshalJSON([]byte(`{"method": 42}`)); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for unknown integrator, got %v", err)
+	}
+	if err := plant2.UnmarshalJSON([]byte(`{"a": [1]}`)); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for short matrix, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package control
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/queue"
+)
+
+// store one input sample of a delay line
+type delaySample struct {
+	t, v float64
+}
+
+// delay a signal by a fixed transport time, y(t) = u(t - delay)
+//
+// the delay does not need to be a multiple of the timestep, the output is linearly
+// interpolated between the stored samples, and the input before the first call is taken as zero
+//
+// the timestep may vary between calls
+//
+// this type is not safe for concurrent use
+type Delay[T c.Float] struct {
+	delay float64
+
+	t    float64
+	prev delaySample
+	buf  *queue.Deque[delaySample]
+}
+
+// create a delay of the given time, negative delays are treated as zero
+func NewDelay[T c.Float](delay T) *Delay[T] {
+	return &Delay[T]{
+		delay: max(0, float64(delay)),
+		buf:   queue.NewDeque[delaySample](),
+	}
+}
+
+// return the delay time
+//
+// time: O(1)
+func (d *Delay[T]) Time() T {
+	return T(d.delay)
+}
+
+// reset internal state
+//
+// time: O(n) where n is the number of stored samples
+func (d *Delay[T]) Reset() {
+	d.t = 0
+	d.prev = delaySample{}
+	d.buf.Clear()
+}
+
+// push input u held over a timestep dt and return the delayed output
+//
+// return the last output if dt is not positive
+//
+// time: O(1) amortised
+func (d *Delay[T]) Compute(u, dt T) T {
+	if dt > 0 {
+		d.t += float64(dt)
+		d.buf.PushBack(delaySample{t: d.t, v: float64(u)})
+	}
+
+	// drop every sample at or before the delayed time, keeping the newest of them
+	target := d.t - d.delay
+	eps := 1e-9 * max(float64(dt), 1e-12)
+	for {
+		front, ok := d.buf.PeekFront()
+		if !ok || front.t > target+eps {
+			break
+		}
+		d.prev, _ = d.buf.PopFront()
+	}
+
+	next, ok := d.buf.PeekFront()
+	if !ok {
+		return T(d.prev.v)
+	}
+
+	frac := min(1, max(0, (target-d.prev.t)/(next.t-d.prev.t)))
+	return T(d.prev.v + frac*(next.v-d.prev.v))
+}
@@ -0,0 +1,77 @@
+package control
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ==================
+// static feedforward
+// ==================
+
+// feed the setpoint forward through a constant gain, u = k*r
+//
+// typical uses are a torque constant inverse or a friction compensation
+type StaticFeedforward[T c.Float] struct {
+	gain, offset T
+}
+
+// create a static feedforward with u = gain*r + offset
+func NewStaticFeedforward[T c.Float](gain, offset T) StaticFeedforward[T] {
+	return StaticFeedforward[T]{gain: gain, offset: offset}
+}
+
+// compute the feedforward term for setpoint r
+//
+// time: O(1)
+func (f StaticFeedforward[T]) Compute(r, dt T) T {
+	return f.gain*r + f.offset
+}
+
+// ======================
+// derivative feedforward
+// ======================
+
+// feed forward the rate of change of the setpoint, u = k*dr/dt
+//
+// in a position loop it supplies the velocity the inner loop would otherwise have to lag for
+//
+// this type is not safe for concurrent use
+type DerivativeFeedforward[T c.Float] struct {
+	gain T
+
+	prev  T
+	ready bool
+}
+
+// create a derivative feedforward with gain k
+func NewDerivativeFeedforward[T c.Float](gain T) *DerivativeFeedforward[T] {
+	return &DerivativeFeedforward[T]{gain: gain}
+}
+
+// reset internal state
+//
+// time: O(1)
+func (f *DerivativeFeedforward[T]) Reset() {
+	f.prev = 0
+	f.ready = false
+}
+
+// compute the feedforward term for setpoint r
+//
+// return 0 on the first call or if dt is not positive
+//
+// time: O(1)
+func (f *DerivativeFeedforward[T]) Compute(r, dt T) T {
+	if dt <= 0 {
+		return 0
+	}
+
+	var out T
+	if f.ready {
+		out = f.gain * (r - f.prev) / dt
+	}
+	f.prev = r
+	f.ready = true
+
+	return out
+}
@@ -1,6 +1,10 @@
 package control
 
-import c "github.com/vistormu/go-dsa/constraints"
+import (
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
 
 // simulate a first order siso lti system in state space form
 //
@@ -9,12 +13,17 @@ import c "github.com/vistormu/go-dsa/constraints"
 //	x' = a*x + b*u
 //	y  = c*x + d*u
 //
-// discretise with forward euler on each compute call
+// discretise with forward euler on each compute call unless another integrator is selected
 //
 // this type is not safe for concurrent use
 type FirstOrder[T c.Float] struct {
 	a, b, c, d T
 	x          T
+
+	// discrete coefficients cached for the last dt
+	method Integrator
+	dt     T
+	ad, bd T
 }
 
 // create a first order system from state space coefficients
@@ -45,6 +54,15 @@ func NewFirstOrder[T c.Float](k, tau T) *FirstOrder[T] {
 	}
 }
 
+// select the integration scheme and return the system for chaining
+//
+// time: O(1)
+func (s *FirstOrder[T]) WithIntegrator(method Integrator) *FirstOrder[T] {
+	s.method = method
+	s.dt = 0
+	return s
+}
+
 // reset internal state
 //
 // time: O(1)
@@ -62,8 +80,28 @@ func (s *FirstOrder[T]) Compute(u, dt T) T {
 		return 0
 	}
 
-	// forward euler: x_{k+1} = x_k + dt*(a*x_k + b*u_k)
-	s.x += dt * (s.a*s.x + s.b*u)
+	if dt != s.dt {
+		ad, bd := discretiseScalar(float64(s.a), float64(s.b), float64(dt), s.method)
+		s.ad, s.bd = T(ad), T(bd)
+		s.dt = dt
+	}
+
+	// x_{k+1} = ad*x_k + bd*u_k
+	s.x = s.ad*s.x + s.bd*u
 
 	return s.c*s.x + s.d*u
 }
+
+// evaluate the continuous frequency response at w rad/s
+//
+//	g(jw) = c*b / (jw - a) + d
+//
+// time: O(1)
+func (s *FirstOrder[T]) Freq(w float64) complex128 {
+	jw := complex(0, w)
+	den := jw - complex(float64(s.a), 0)
+	if den == 0 {
+		return cmplx.Inf()
+	}
+	return complex(float64(s.c*s.b), 0)/den + complex(float64(s.d), 0)
+}
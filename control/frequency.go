@@ -0,0 +1,243 @@
+package control
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// any continuous siso system that can be evaluated along the imaginary axis
+//
+// FirstOrder, SecondOrder, StateSpace, Siso and TransferFunction satisfy this interface
+type FrequencySystem interface {
+	Freq(w float64) complex128
+}
+
+// store the frequency response at a single frequency
+type FrequencyPoint struct {
+	// frequency in rad/s
+	W float64
+
+	// magnitude as a ratio and in decibels
+	Mag   float64
+	MagDb float64
+
+	// unwrapped phase in degrees
+	Phase float64
+
+	// real and imaginary parts for nyquist plots
+	Re float64
+	Im float64
+}
+
+// store the stability margins of an open loop system
+type Margins struct {
+	// factor by which the loop gain can grow before instability, +inf if the phase never reaches -180 degrees
+	GainMargin float64
+
+	// extra phase lag in degrees the loop tolerates at the gain crossover, +inf if the gain never crosses 1
+	PhaseMargin float64
+
+	// frequency in rad/s where the phase crosses -180 degrees, 0 if it never does
+	PhaseCrossover float64
+
+	// frequency in rad/s where the magnitude crosses 1, 0 if it never does
+	GainCrossover float64
+}
+
+// return the gain margin in decibels
+//
+// time: O(1)
+func (m Margins) GainMarginDb() float64 {
+	return 20 * math.Log10(m.GainMargin)
+}
+
+// return n logarithmically spaced frequencies from lo to hi inclusive
+//
+// return nil if the bounds are not positive or n is less than 2
+//
+// time: O(n)
+func LogSpace(lo, hi float64, n int) []float64 {
+	if lo <= 0 || hi <= 0 || n < 2 {
+		return nil
+	}
+
+	out := make([]float64, n)
+	a, b := math.Log10(lo), math.Log10(hi)
+	for i := range n {
+		out[i] = math.Pow(10, a+(b-a)*float64(i)/float64(n-1))
+	}
+	return out
+}
+
+// evaluate magnitude and phase of sys over the frequencies w in rad/s
+//
+// the phase is unwrapped along w so it stays continuous
+//
+// time: O(len(w)*cost(sys))
+func Bode(sys FrequencySystem, w []float64) []FrequencyPoint {
+	out := make([]FrequencyPoint, len(w))
+
+	prev := 0.0
+	for i, wi := range w {
+		g := sys.Freq(wi)
+		mag := cmplx.Abs(g)
+
+		phase := cmplx.Phase(g) * 180 / math.Pi
+		if i > 0 {
+			phase = unwrap(phase, prev)
+		}
+		prev = phase
+
+		out[i] = FrequencyPoint{
+			W:     wi,
+			Mag:   mag,
+			MagDb: 20 * math.Log10(mag),
+			Phase: phase,
+			Re:    real(g),
+			Im:    imag(g),
+		}
+	}
+
+	return out
+}
+
+// evaluate the complex response of sys over the frequencies w in rad/s
+//
+// time: O(len(w)*cost(sys))
+func Nyquist(sys FrequencySystem, w []float64) []complex128 {
+	out := make([]complex128, len(w))
+	for i, wi := range w {
+		out[i] = sys.Freq(wi)
+	}
+	return out
+}
+
+// arrange frequency points as csv columns for csv.Save
+//
+// columns are w, mag, mag_db, phase, re and im
+//
+// time: O(n)
+func BodeColumns(points []FrequencyPoint) map[string][]any {
+	cols := map[string][]any{
+		"w":      make([]any, len(points)),
+		"mag":    make([]any, len(points)),
+		"mag_db": make([]any, len(points)),
+		"phase":  make([]any, len(points)),
+		"re":     make([]any, len(points)),
+		"im":     make([]any, len(points)),
+	}
+
+	for i, p := range points {
+		cols["w"][i] = p.W
+		cols["mag"][i] = p.Mag
+		cols["mag_db"][i] = p.MagDb
+		cols["phase"][i] = p.Phase
+		cols["re"][i] = p.Re
+		cols["im"][i] = p.Im
+	}
+
+	return cols
+}
+
+// compute gain and phase margins of the open loop sys
+//
+// crossings are located on a log grid of n points between lo and hi rad/s and refined by bisection
+//
+// when several crossings exist, the smallest margin is reported
+//
+// time: O(n*cost(sys))
+func StabilityMargins(sys FrequencySystem, lo, hi float64, n int) Margins {
+	m := Margins{GainMargin: math.Inf(1), PhaseMargin: math.Inf(1)}
+
+	points := Bode(sys, LogSpace(lo, hi, n))
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+
+		// gain crossover, |g| = 1
+		if (a.Mag-1)*(b.Mag-1) <= 0 && a.Mag != b.Mag {
+			w := bisectLog(a.W, b.W, func(w float64) float64 {
+				return cmplx.Abs(sys.Freq(w)) - 1
+			})
+			phase := unwrap(cmplx.Phase(sys.Freq(w))*180/math.Pi, a.Phase)
+			if pm := 180 + wrap180(phase); pm < m.PhaseMargin {
+				m.PhaseMargin = pm
+				m.GainCrossover = w
+			}
+		}
+
+		// phase crossover, phase = -180 + k*360
+		target := -180 + 360*math.Floor((max(a.Phase, b.Phase)+180)/360)
+		if (a.Phase-target)*(b.Phase-target) <= 0 && a.Phase != b.Phase {
+			w := bisectLog(a.W, b.W, func(w float64) float64 {
+				return unwrap(cmplx.Phase(sys.Freq(w))*180/math.Pi, a.Phase) - target
+			})
+			if gm := 1 / cmplx.Abs(sys.Freq(w)); gm < m.GainMargin {
+				m.GainMargin = gm
+				m.PhaseCrossover = w
+			}
+		}
+	}
+
+	return m
+}
+
+// return the frequency in rad/s where the magnitude first drops 3 db below the dc gain
+//
+// the dc gain is taken at the lowest grid frequency lo
+//
+// return 0 if the magnitude never drops below the threshold on the grid
+//
+// time: O(n*cost(sys))
+func Bandwidth(sys FrequencySystem, lo, hi float64, n int) float64 {
+	w := LogSpace(lo, hi, n)
+	if w == nil {
+		return 0
+	}
+
+	ref := cmplx.Abs(sys.Freq(lo)) / math.Sqrt2
+	f := func(w float64) float64 {
+		return cmplx.Abs(sys.Freq(w)) - ref
+	}
+
+	prev := f(w[0])
+	for i := 1; i < len(w); i++ {
+		cur := f(w[i])
+		if prev >= 0 && cur < 0 {
+			return bisectLog(w[i-1], w[i], f)
+		}
+		prev = cur
+	}
+
+	return 0
+}
+
+// shift phase by multiples of 360 degrees so it lies closest to ref
+func unwrap(phase, ref float64) float64 {
+	return phase + 360*math.Round((ref-phase)/360)
+}
+
+// map phase to (-180, 180] so that 180 + phase is the lag that can be added before reaching -180
+func wrap180(phase float64) float64 {
+	p := math.Mod(phase, 360)
+	if p > 180 {
+		p -= 360
+	} else if p <= -180 {
+		p += 360
+	}
+	return p
+}
+
+// find a root of f between lo and hi by bisection in log frequency
+func bisectLog(lo, hi float64, f func(float64) float64) float64 {
+	fl := f(lo)
+	for range 60 {
+		mid := math.Sqrt(lo * hi)
+		fm := f(mid)
+		if fl*fm <= 0 {
+			hi = mid
+		} else {
+			lo, fl = mid, fm
+		}
+	}
+	return math.Sqrt(lo * hi)
+}
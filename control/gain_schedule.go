@@ -0,0 +1,430 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/csv"
+)
+
+// returned when a gain schedule is empty, has repeated scheduling values, sets alpha on only
+// some points or cannot be parsed
+var ErrSchedule = errors.New("control: invalid gain schedule")
+
+// select how a gain schedule interpolates between its points
+type Interpolation int
+
+const (
+	// piecewise linear interpolation
+	Linear Interpolation = iota
+
+	// monotone piecewise cubic hermite interpolation
+	//
+	// smooth, and never overshoots the table values between two points
+	Cubic
+)
+
+// return the name of the interpolation mode
+func (m Interpolation) String() string {
+	switch m {
+	case Linear:
+		return "linear"
+	case Cubic:
+		return "cubic"
+	default:
+		return "unknown"
+	}
+}
+
+// parse an interpolation mode from its name
+func parseInterpolation(s string) (Interpolation, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "linear":
+		return Linear, true
+	case "cubic":
+		return Cubic, true
+	default:
+		return 0, false
+	}
+}
+
+// store the pid parameters at one operating point
+type GainPoint[T c.Float] struct {
+	// value of the scheduling variable
+	X T `json:"x"`
+
+	Kp T `json:"kp"`
+	Ki T `json:"ki"`
+	Kd T `json:"kd"`
+
+	// derivative smoothing factor, nil leaves the alpha of the pid in place
+	//
+	// either every point of a schedule sets it or none does
+	Alpha *T `json:"alpha,omitempty"`
+}
+
+// return a copy of the point that does not share its alpha, moved to scheduling value x
+func (p GainPoint[T]) at(x T) GainPoint[T] {
+	p.X = x
+	if p.Alpha != nil {
+		alpha := *p.Alpha
+		p.Alpha = &alpha
+	}
+	return p
+}
+
+// =============
+// gain schedule
+// =============
+
+// interpolate pid parameters from a table indexed by a scheduling variable
+//
+// outside the table the first or last point is held
+type GainSchedule[T c.Float] struct {
+	points []GainPoint[T]
+	mode   Interpolation
+	alpha  bool
+
+	// hermite slopes of kp, ki, kd and alpha at each point
+	slopes [4][]float64
+}
+
+// create a gain schedule from its points, in any order
+//
+// return ErrSchedule if there are no points, two share the same scheduling value or alpha is
+// set on some points but not all
+//
+// time: O(n log n)
+func NewGainSchedule[T c.Float](points []GainPoint[T], mode Interpolation) (*GainSchedule[T], error) {
+	if len(points) == 0 {
+		return nil, ErrSchedule
+	}
+
+	pts := make([]GainPoint[T], len(points))
+	for i, p := range points {
+		pts[i] = p.at(p.X)
+	}
+	slices.SortFunc(pts, func(a, b GainPoint[T]) int {
+		switch {
+		case a.X < b.X:
+			return -1
+		case a.X > b.X:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	g := &GainSchedule[T]{points: pts, mode: mode, alpha: pts[0].Alpha != nil}
+	for i, p := range pts {
+		if i > 0 && p.X == pts[i-1].X {
+			return nil, ErrSchedule
+		}
+		if (p.Alpha != nil) != g.alpha {
+			return nil, ErrSchedule
+		}
+	}
+
+	if mode == Cubic {
+		for f := range g.slopes {
+			g.slopes[f] = g.hermiteSlopes(f)
+		}
+	}
+
+	return g, nil
+}
+
+// read a gain schedule from a json file
+//
+// the file holds an object with an optional interpolation name, linear by default,
+// and the list of points
+//
+//	{"interpolation": "cubic", "points": [{"x": 0, "kp": 1, "ki": 0.5, "kd": 0}]}
+//
+// return ErrSchedule wrapped with the cause if the file cannot be parsed
+func LoadGainScheduleJSON[T c.Float](path string) (*GainSchedule[T], error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Interpolation string         `json:"interpolation"`
+		Points        []GainPoint[T] `json:"points"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchedule, err)
+	}
+
+	mode, ok := parseInterpolation(doc.Interpolation)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown interpolation %q", ErrSchedule, doc.Interpolation)
+	}
+	return NewGainSchedule(doc.Points, mode)
+}
+
+// read a gain schedule from a csv file with columns x, kp, ki, kd and optionally alpha
+//
+// return ErrSchedule wrapped with the cause if a column is missing or malformed
+func LoadGainScheduleCSV[T c.Float](path string, mode Interpolation) (*GainSchedule[T], error) {
+	data, err := csv.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return GainScheduleFromColumns[T](data, mode)
+}
+
+// build a gain schedule from csv columns as returned by csv.Read
+//
+// the columns are x, kp, ki, kd and optionally alpha
+//
+// return ErrSchedule wrapped with the cause if a column is missing, malformed or of a different length
+func GainScheduleFromColumns[T c.Float](data map[string][]any, mode Interpolation) (*GainSchedule[T], error) {
+	names := []string{"x", "kp", "ki", "kd", "alpha"}
+	cols := make([][]float64, len(names))
+	for i, name := range names {
+		col, ok := data[name]
+		if !ok {
+			if name == "alpha" {
+				continue
+			}
+			return nil, fmt.Errorf("%w: missing column %q", ErrSchedule, name)
+		}
+
+		v, err := csv.Floats(col)
+		if err != nil {
+			return nil, fmt.Errorf("%w: column %q: %v", ErrSchedule, name, err)
+		}
+		if i > 0 && len(v) != len(cols[0]) {
+			return nil, fmt.Errorf("%w: column %q has %d rows, want %d", ErrSchedule, name, len(v), len(cols[0]))
+		}
+		cols[i] = v
+	}
+
+	points := make([]GainPoint[T], len(cols[0]))
+	for i := range points {
+		points[i] = GainPoint[T]{X: T(cols[0][i]), Kp: T(cols[1][i]), Ki: T(cols[2][i]), Kd: T(cols[3][i])}
+		if cols[4] != nil {
+			alpha := T(cols[4][i])
+			points[i].Alpha = &alpha
+		}
+	}
+	return NewGainSchedule(points, mode)
+}
+
+// return the interpolation mode
+//
+// time: O(1)
+func (g *GainSchedule[T]) Interpolation() Interpolation {
+	return g.mode
+}
+
+// return a copy of the points sorted by scheduling value
+//
+// time: O(n)
+func (g *GainSchedule[T]) Points() []GainPoint[T] {
+	out := make([]GainPoint[T], len(g.points))
+	for i, p := range g.points {
+		out[i] = p.at(p.X)
+	}
+	return out
+}
+
+// return the interpolated parameters at scheduling value x
+//
+// time: O(log n)
+func (g *GainSchedule[T]) At(x T) GainPoint[T] {
+	pts := g.points
+	n := len(pts)
+	if x <= pts[0].X {
+		return pts[0].at(x)
+	}
+	if x >= pts[n-1].X {
+		return pts[n-1].at(x)
+	}
+
+	// first point above x
+	i, _ := slices.BinarySearchFunc(pts, x, func(p GainPoint[T], x T) int {
+		switch {
+		case p.X < x:
+			return -1
+		case p.X > x:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if pts[i].X == x {
+		return pts[i].at(x)
+	}
+
+	lo, hi := pts[i-1], pts[i]
+	h := float64(hi.X - lo.X)
+	s := float64(x-lo.X) / h
+
+	out := GainPoint[T]{X: x}
+	dst := []*T{&out.Kp, &out.Ki, &out.Kd}
+	if g.alpha {
+		out.Alpha = new(T)
+		dst = append(dst, out.Alpha)
+	}
+	for f, d := range dst {
+		y0, y1 := gainField(lo, f), gainField(hi, f)
+		if g.mode != Cubic {
+			*d = T(y0 + s*(y1-y0))
+			continue
+		}
+
+		// cubic hermite basis
+		m0, m1 := g.slopes[f][i-1], g.slopes[f][i]
+		s2, s3 := s*s, s*s*s
+		*d = T((2*s3-3*s2+1)*y0 + (s3-2*s2+s)*h*m0 + (-2*s3+3*s2)*y1 + (s3-s2)*h*m1)
+	}
+	return out
+}
+
+// return the fritsch carlson monotone slopes of parameter f at every point
+func (g *GainSchedule[T]) hermiteSlopes(f int) []float64 {
+	pts := g.points
+	n := len(pts)
+	m := make([]float64, n)
+	if n < 2 {
+		return m
+	}
+
+	delta := make([]float64, n-1)
+	for i := range delta {
+		delta[i] = (gainField(pts[i+1], f) - gainField(pts[i], f)) / float64(pts[i+1].X-pts[i].X)
+	}
+
+	m[0], m[n-1] = delta[0], delta[n-2]
+	for i := 1; i < n-1; i++ {
+		if delta[i-1]*delta[i] > 0 {
+			m[i] = (delta[i-1] + delta[i]) / 2
+		}
+	}
+
+	// limit the slopes so each interval stays monotone
+	for i, d := range delta {
+		if d == 0 {
+			m[i], m[i+1] = 0, 0
+			continue
+		}
+		a, b := m[i]/d, m[i+1]/d
+		if r := math.Hypot(a, b); r > 3 {
+			m[i] = 3 * a / r * d
+			m[i+1] = 3 * b / r * d
+		}
+	}
+	return m
+}
+
+// return parameter f of a point, in the order kp, ki, kd, alpha
+func gainField[T c.Float](p GainPoint[T], f int) float64 {
+	switch f {
+	case 0:
+		return float64(p.Kp)
+	case 1:
+		return float64(p.Ki)
+	case 2:
+		return float64(p.Kd)
+	default:
+		if p.Alpha == nil {
+			return 0
+		}
+		return float64(*p.Alpha)
+	}
+}
+
+// =============
+// scheduled pid
+// =============
+
+// run a pid whose parameters follow a gain schedule
+//
+// gain changes go through Pid.SetGains, so transitions between operating points are bumpless,
+// including points where ki is zero
+//
+// this type is not safe for concurrent use
+type ScheduledPid[T c.Float] struct {
+	pid      *Pid[T]
+	schedule *GainSchedule[T]
+	x        T
+}
+
+// create a scheduled pid and load the gains at scheduling value x0
+//
+// the other settings of pid, such as limits and anti windup, are kept
+func NewScheduledPid[T c.Float](pid *Pid[T], schedule *GainSchedule[T], x0 T) *ScheduledPid[T] {
+	s := &ScheduledPid[T]{pid: pid, schedule: schedule}
+
+	p := schedule.At(x0)
+	pid.kp, pid.ki, pid.kd = p.Kp, p.Ki, p.Kd
+	if p.Alpha != nil {
+		pid.alpha = *p.Alpha
+	}
+	s.x = x0
+
+	return s
+}
+
+// return the wrapped pid
+//
+// time: O(1)
+func (s *ScheduledPid[T]) Pid() *Pid[T] {
+	return s.pid
+}
+
+// return the current scheduling value
+//
+// time: O(1)
+func (s *ScheduledPid[T]) Operating() T {
+	return s.x
+}
+
+// move to scheduling value x and update the gains without a bump in the output
+//
+// time: O(log n)
+func (s *ScheduledPid[T]) Schedule(x T) {
+	s.x = x
+
+	p := s.schedule.At(x)
+	s.pid.SetGains(p.Kp, p.Ki, p.Kd)
+	if p.Alpha != nil {
+		s.pid.SetAlpha(*p.Alpha)
+	}
+}
+
+// reset the wrapped pid
+//
+// time: O(1)
+func (s *ScheduledPid[T]) Reset() {
+	s.pid.Reset()
+}
+
+// tell the wrapped pid that its last output was limited externally to out
+//
+// time: O(1)
+func (s *ScheduledPid[T]) Track(out T) {
+	s.pid.Track(out)
+}
+
+// compute output given an error value and dt with the current gains
+//
+// time: O(1)
+func (s *ScheduledPid[T]) Compute(err, dt T) T {
+	return s.pid.Compute(err, dt)
+}
+
+// compute output given a setpoint, a process value and dt with the current gains
+//
+// time: O(1)
+func (s *ScheduledPid[T]) ComputeSp(sp, pv, dt T) T {
+	return s.pid.ComputeSp(sp, pv, dt)
+}
@@ -0,0 +1,321 @@
+package control
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+var (
+	// returned when the samples are too few, mismatched or not informative enough to fit a model
+	ErrInsufficientData = errors.New("control: not enough informative samples to identify the model")
+
+	// returned when the identified model has no continuous equivalent of the requested form
+	ErrModelMismatch = errors.New("control: identified model does not match the requested structure")
+)
+
+// store the quality of an identified model
+//
+// the model is simulated from the recorded input alone, so the figures measure
+// how well it reproduces the output rather than how well it predicts one step ahead
+type FitQuality[T c.Float] struct {
+	// coefficient of determination, 1 for a perfect fit, 0 for the mean of y
+	R2 T
+
+	// root mean square of the residuals
+	Rmse T
+
+	// recorded minus simulated output for every sample
+	Residuals []T
+}
+
+// ===
+// arx
+// ===
+
+// store a discrete arx model
+//
+//	y_k + a_1*y_{k-1} + ... + a_na*y_{k-na} = b_1*u_{k-nk} + ... + b_nb*u_{k-nk-nb+1}
+type Arx[T c.Float] struct {
+	A []T
+	B []T
+
+	// input delay in samples
+	Delay int
+}
+
+// fit an arx model with na output and nb input coefficients and delay nk to recorded samples
+//
+// u and y must be uniformly sampled and deviations from the operating point
+//
+// the coefficients minimise the squared one step prediction error
+//
+// return ErrDimension if the orders are invalid and ErrInsufficientData if the data cannot determine them
+//
+// time: O(n*(na+nb)^2 + (na+nb)^3)
+func FitArx[T c.Float](u, y []T, na, nb, nk int) (Arx[T], FitQuality[T], error) {
+	if na < 0 || nb < 1 || nk < 0 {
+		return Arx[T]{}, FitQuality[T]{}, ErrDimension
+	}
+	if len(u) != len(y) {
+		return Arx[T]{}, FitQuality[T]{}, ErrInsufficientData
+	}
+
+	theta, ok := arxLeastSquares(u, y, na, nb, nk)
+	if !ok {
+		return Arx[T]{}, FitQuality[T]{}, ErrInsufficientData
+	}
+
+	m := Arx[T]{A: make([]T, na), B: make([]T, nb), Delay: nk}
+	for i := range na {
+		m.A[i] = T(theta[i])
+	}
+	for i := range nb {
+		m.B[i] = T(theta[na+i])
+	}
+
+	return m, fitQuality(y, m.Simulate(u)), nil
+}
+
+// simulate the model output for input u from rest
+//
+// time: O(n*(na+nb))
+func (m Arx[T]) Simulate(u []T) []T {
+	y := make([]T, len(u))
+	for k := range y {
+		var acc float64
+		for i, a := range m.A {
+			if j := k - 1 - i; j >= 0 {
+				acc -= float64(a) * float64(y[j])
+			}
+		}
+		for i, b := range m.B {
+			if j := k - m.Delay - i; j >= 0 {
+				acc += float64(b) * float64(u[j])
+			}
+		}
+		y[k] = T(acc)
+	}
+	return y
+}
+
+// return the steady state gain of the model, +inf if it has a pole at z = 1
+//
+// time: O(na+nb)
+func (m Arx[T]) DcGain() T {
+	num, den := 0.0, 1.0
+	for _, b := range m.B {
+		num += float64(b)
+	}
+	for _, a := range m.A {
+		den += float64(a)
+	}
+	if den == 0 {
+		return T(math.Inf(1))
+	}
+	return T(num / den)
+}
+
+// solve the arx normal equations and return the stacked coefficients a then b
+func arxLeastSquares[T c.Float](u, y []T, na, nb, nk int) ([]float64, bool) {
+	p := na + nb
+	start := max(na, nk+nb-1)
+	if len(y)-start < p {
+		return nil, false
+	}
+
+	// accumulate phi'*phi and phi'*y row by row
+	ata := linalg.New(p, p)
+	aty := linalg.New(p, 1)
+	row := make([]float64, p)
+	for k := start; k < len(y); k++ {
+		for i := range na {
+			row[i] = -float64(y[k-1-i])
+		}
+		for i := range nb {
+			row[na+i] = float64(u[k-nk-i])
+		}
+
+		for i, ri := range row {
+			aty.Data[i] += ri * float64(y[k])
+			for j, rj := range row {
+				ata.Data[i*p+j] += ri * rj
+			}
+		}
+	}
+
+	sol, ok := ata.Solve(aty)
+	if !ok || cond(ata) > 1e14 || !linalg.Finite(sol.Data) {
+		return nil, false
+	}
+	return sol.Data, true
+}
+
+// =====
+// fopdt
+// =====
+
+// fit a first order plus dead time model to recorded input and output samples
+//
+// t, u and y hold uniformly spaced sample times, inputs and outputs as deviations from the operating point,
+// and the input may be any sufficiently exciting signal, not only a step
+//
+// every dead time up to maxDelay is tried and the one with the best fit is kept
+//
+// the model is fitted in discrete time, exact for a zoh input, and converted back to continuous time
+//
+// return ErrInsufficientData if the samples cannot determine the model and ErrModelMismatch if the
+// best discrete pole does not belong to a stable first order system
+//
+// time: O(n*maxDelay/dt)
+func FitFopdt[T c.Float](t, u, y []T, maxDelay T) (Fopdt[T], FitQuality[T], error) {
+	dt, ok := samplePeriod(t, u, y)
+	if !ok {
+		return Fopdt[T]{}, FitQuality[T]{}, ErrInsufficientData
+	}
+
+	maxNk := max(0, int(math.Round(float64(maxDelay)/dt)))
+
+	var best Arx[T]
+	var bestQ FitQuality[T]
+	found := false
+	for nk := 1; nk <= maxNk+1; nk++ {
+		m, q, err := FitArx(u, y, 1, 1, nk)
+		if err != nil {
+			continue
+		}
+		if pole := -float64(m.A[0]); pole <= 0 || pole >= 1 {
+			continue
+		}
+		if !found || q.R2 > bestQ.R2 {
+			best, bestQ, found = m, q, true
+		}
+	}
+	if !found {
+		return Fopdt[T]{}, FitQuality[T]{}, ErrModelMismatch
+	}
+
+	// y_k = pole*y_{k-1} + b*u_{k-nk} with pole = e^(-dt/tau) and b = k*(1 - pole)
+	pole := -float64(best.A[0])
+	tau := -dt / math.Log(pole)
+	k := float64(best.B[0]) / (1 - pole)
+	theta := float64(best.Delay-1) * dt
+
+	return Fopdt[T]{K: T(k), Tau: T(tau), Theta: T(theta)}, bestQ, nil
+}
+
+// return a first order system with the gain and time constant of the model, dropping the dead time
+//
+// chain it with NewDelay(m.Theta) to reproduce the dead time
+//
+// time: O(1)
+func (m Fopdt[T]) System() *FirstOrder[T] {
+	return NewFirstOrder(m.K, m.Tau)
+}
+
+// ============
+// second order
+// ============
+
+// store the parameters of a continuous second order model k*wn^2/(s^2 + 2*zeta*wn*s + wn^2)
+type SecondOrderModel[T c.Float] struct {
+	K    T
+	Wn   T
+	Zeta T
+}
+
+// return a second order system with the parameters of the model
+//
+// time: O(1)
+func (m SecondOrderModel[T]) System() *SecondOrder[T] {
+	return NewSecondOrder(m.K, m.Wn, m.Zeta)
+}
+
+// fit a second order model to recorded input and output samples
+//
+// t, u and y hold uniformly spaced sample times, inputs and outputs as deviations from the operating point
+//
+// a discrete arx model with two poles is fitted, exact for a zoh input, and its poles
+// are mapped back to continuous time with s = ln(z)/dt
+//
+// return ErrInsufficientData if the samples cannot determine the model and ErrModelMismatch if the
+// discrete poles do not belong to a stable second order system
+//
+// time: O(n)
+func FitSecondOrder[T c.Float](t, u, y []T) (SecondOrderModel[T], FitQuality[T], error) {
+	dt, ok := samplePeriod(t, u, y)
+	if !ok {
+		return SecondOrderModel[T]{}, FitQuality[T]{}, ErrInsufficientData
+	}
+
+	m, q, err := FitArx(u, y, 2, 2, 1)
+	if err != nil {
+		return SecondOrderModel[T]{}, FitQuality[T]{}, err
+	}
+
+	// poles of z^2 + a1*z + a2
+	a1, a2 := float64(m.A[0]), float64(m.A[1])
+	disc := cmplx.Sqrt(complex(a1*a1-4*a2, 0))
+	z1 := (complex(-a1, 0) + disc) / 2
+	z2 := (complex(-a1, 0) - disc) / 2
+	if cmplx.Abs(z1) >= 1 || cmplx.Abs(z2) >= 1 || real(z1*z2) <= 0 {
+		return SecondOrderModel[T]{}, FitQuality[T]{}, ErrModelMismatch
+	}
+	if imag(z1) == 0 && (real(z1) <= 0 || real(z2) <= 0) {
+		return SecondOrderModel[T]{}, FitQuality[T]{}, ErrModelMismatch
+	}
+
+	// s1*s2 = wn^2 and s1 + s2 = -2*zeta*wn
+	s1 := cmplx.Log(z1) / complex(dt, 0)
+	s2 := cmplx.Log(z2) / complex(dt, 0)
+	wn := math.Sqrt(real(s1 * s2))
+	zeta := -real(s1+s2) / (2 * wn)
+
+	return SecondOrderModel[T]{K: m.DcGain(), Wn: T(wn), Zeta: T(zeta)}, q, nil
+}
+
+// return the mean sample period of uniformly spaced samples
+func samplePeriod[T c.Float](t, u, y []T) (float64, bool) {
+	n := len(t)
+	if n < 3 || len(u) != n || len(y) != n {
+		return 0, false
+	}
+	dt := float64(t[n-1]-t[0]) / float64(n-1)
+	return dt, dt > 0
+}
+
+// compare recorded and simulated outputs
+func fitQuality[T c.Float](y, sim []T) FitQuality[T] {
+	n := len(y)
+	if n == 0 {
+		return FitQuality[T]{}
+	}
+
+	var mean float64
+	for _, v := range y {
+		mean += float64(v)
+	}
+	mean /= float64(n)
+
+	res := make([]T, n)
+	var ssr, sst float64
+	for i, v := range y {
+		r := float64(v - sim[i])
+		res[i] = T(r)
+		ssr += r * r
+		d := float64(v) - mean
+		sst += d * d
+	}
+
+	r2 := 1.0
+	if sst > 0 {
+		r2 = 1 - ssr/sst
+	} else if ssr > 0 {
+		r2 = 0
+	}
+
+	return FitQuality[T]{R2: T(r2), Rmse: T(math.Sqrt(ssr / float64(n))), Residuals: res}
+}
@@ -0,0 +1,170 @@
+package control
+
+import (
+	"math"
+
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// select how a continuous system is discretised on each compute call
+//
+// the input u is held constant over the step for every scheme
+type Integrator int
+
+const (
+	// explicit first order scheme, x_{k+1} = x_k + dt*f(x_k, u_k)
+	//
+	// cheapest option, unstable when dt is large compared to the fastest pole
+	ForwardEuler Integrator = iota
+
+	// implicit first order scheme, x_{k+1} = x_k + dt*f(x_{k+1}, u_k)
+	//
+	// unconditionally stable for stable plants, adds numerical damping
+	BackwardEuler
+
+	// trapezoidal rule, also known as the bilinear transform
+	//
+	// unconditionally stable for stable plants and preserves oscillation
+	Tustin
+
+	// classic fourth order runge kutta
+	RK4
+
+	// exact zero order hold discretisation through the matrix exponential
+	//
+	// matches the continuous step response at every sample
+	ZOH
+)
+
+// return the name of the integration scheme
+func (i Integrator) String() string {
+	switch i {
+	case ForwardEuler:
+		return "forward_euler"
+	case BackwardEuler:
+		return "backward_euler"
+	case Tustin:
+		return "tustin"
+	case RK4:
+		return "rk4"
+	case ZOH:
+		return "zoh"
+	default:
+		return "unknown"
+	}
+}
+
+// discretise the scalar system x' = a*x + b*u
+//
+// return ad, bd such that x_{k+1} = ad*x_k + bd*u_k
+//
+// fall back to forward euler if an implicit scheme divides by zero, a*dt = 1 for backward
+// euler and a*dt = 2 for tustin, as the matrix version does for a singular matrix
+//
+// time: O(1)
+func discretiseScalar(a, b, dt float64, method Integrator) (float64, float64) {
+	z := a * dt
+
+	switch method {
+	case BackwardEuler:
+		if den := 1 - z; den != 0 {
+			return 1 / den, b * dt / den
+		}
+
+	case Tustin:
+		if den := 1 - z/2; den != 0 {
+			return (1 + z/2) / den, b * dt / den
+		}
+
+	case RK4:
+		ad := 1 + z + z*z/2 + z*z*z/6 + z*z*z*z/24
+		bd := dt * (1 + z/2 + z*z/6 + z*z*z/24) * b
+		return ad, bd
+
+	case ZOH:
+		if a == 0 {
+			return 1, b * dt
+		}
+		ad := math.Exp(z)
+		return ad, (ad - 1) / a * b
+	}
+
+	return 1 + z, b * dt
+}
+
+// discretise the system x' = a*x + b*u where a is n by n and b is n by m
+//
+// return ad, bd such that x_{k+1} = ad*x_k + bd*u_k
+//
+// fall back to forward euler if an implicit scheme hits a singular matrix
+//
+// time: O(n^3)
+func discretise(a, b linalg.Matrix, dt float64, method Integrator) (linalg.Matrix, linalg.Matrix) {
+	n := a.Rows
+	id := linalg.Identity(n)
+	ah := a.Scale(dt)
+	bh := b.Scale(dt)
+
+	switch method {
+	case BackwardEuler:
+		lhs := id.Sub(ah)
+		ad, ok1 := lhs.Inverse()
+		bd, ok2 := lhs.Solve(bh)
+		if ok1 && ok2 {
+			return ad, bd
+		}
+
+	case Tustin:
+		lhs := id.Sub(ah.Scale(0.5))
+		ad, ok1 := lhs.Solve(id.Add(ah.Scale(0.5)))
+		bd, ok2 := lhs.Solve(bh)
+		if ok1 && ok2 {
+			return ad, bd
+		}
+
+	case RK4:
+		// ad = sum_{k=0}^{4} (a*dt)^k / k!
+		// bd = dt * sum_{k=0}^{3} (a*dt)^k / (k+1)! * b
+		ad := id.Clone()
+		phi := id.Clone()
+		term := id.Clone()
+		fact := 1.0
+		for k := 1; k <= 4; k++ {
+			term = term.Mul(ah)
+			fact *= float64(k)
+			ad = ad.Add(term.Scale(1 / fact))
+			if k < 4 {
+				phi = phi.Add(term.Scale(1 / (fact * float64(k+1))))
+			}
+		}
+		return ad, phi.Mul(bh)
+
+	case ZOH:
+		// exp([a b; 0 0]*dt) = [ad bd; 0 i]
+		m := b.Cols
+		aug := linalg.New(n+m, n+m)
+		for i := range n {
+			for j := range n {
+				aug.Set(i, j, ah.At(i, j))
+			}
+			for j := range m {
+				aug.Set(i, n+j, bh.At(i, j))
+			}
+		}
+		e := linalg.Expm(aug)
+
+		ad := linalg.New(n, n)
+		bd := linalg.New(n, m)
+		for i := range n {
+			for j := range n {
+				ad.Set(i, j, e.At(i, j))
+			}
+			for j := range m {
+				bd.Set(i, j, e.At(i, n+j))
+			}
+		}
+		return ad, bd
+	}
+
+	return id.Add(ah), bh
+}
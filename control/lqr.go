@@ -0,0 +1,112 @@
+package control
+
+import (
+	"errors"
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+var (
+	// returned when the riccati iteration does not settle
+	ErrNotConverged = errors.New("control: riccati iteration did not converge")
+
+	// returned when a matrix that must be inverted is singular
+	ErrSingular = errors.New("control: singular matrix")
+)
+
+// maximum number of riccati iterations before giving up
+const riccatiIterations = 100000
+
+// compute the discrete time linear quadratic regulator gain
+//
+// minimise the cost
+//
+//	sum x_k'*q*x_k + u_k'*r*u_k
+//
+// subject to x_{k+1} = a*x_k + b*u_k, where a is nxn, b is nxm, q is nxn and r is mxm
+//
+// the optimal control is u_k = -k*x_k, k is returned as an mxn matrix
+//
+// the discrete algebraic riccati equation is solved by fixed point iteration
+//
+//	p = q + a'*p*a - a'*p*b*(r + b'*p*b)^-1*b'*p*a
+//
+// use StateSpace.Discrete to obtain a and b from a continuous model
+//
+// return ErrDimension if the shapes are inconsistent, ErrSingular if r + b'*p*b cannot be inverted
+// and ErrNotConverged if (a, b) is not stabilisable
+//
+// time: O(iterations*(n^3 + m^3))
+func Dlqr[T c.Float](a, b, q, r [][]T) ([][]T, error) {
+	am, ok := linalg.FromRows(a)
+	if !ok || am.Rows != am.Cols {
+		return nil, ErrDimension
+	}
+	bm, ok := linalg.FromRows(b)
+	if !ok || bm.Rows != am.Rows {
+		return nil, ErrDimension
+	}
+	qm, ok := linalg.FromRows(q)
+	if !ok || qm.Rows != am.Rows || qm.Cols != am.Rows {
+		return nil, ErrDimension
+	}
+	rm, ok := linalg.FromRows(r)
+	if !ok || rm.Rows != bm.Cols || rm.Cols != bm.Cols {
+		return nil, ErrDimension
+	}
+
+	k, _, err := dare(am, bm, qm, rm)
+	if err != nil {
+		return nil, err
+	}
+	return linalg.ToRows[T](k), nil
+}
+
+// solve the discrete algebraic riccati equation and return the gain and the cost matrix
+func dare(a, b, q, r linalg.Matrix) (linalg.Matrix, linalg.Matrix, error) {
+	at, bt := a.T(), b.T()
+
+	p := q.Clone()
+	for range riccatiIterations {
+		k, ok := riccatiGain(a, b, bt, r, p)
+		if !ok {
+			return linalg.Matrix{}, linalg.Matrix{}, ErrSingular
+		}
+
+		// p = q + a'*p*(a - b*k)
+		next := q.Add(at.Mul(p).Mul(a.Sub(b.Mul(k))))
+		for i := range next.Rows {
+			for j := range i {
+				// keep p symmetric against rounding
+				v := (next.At(i, j) + next.At(j, i)) / 2
+				next.Set(i, j, v)
+				next.Set(j, i, v)
+			}
+		}
+
+		diff := next.Sub(p).NormInf()
+		scale := max(1, next.NormInf())
+		if math.IsNaN(diff) || math.IsInf(scale, 0) {
+			return linalg.Matrix{}, linalg.Matrix{}, ErrNotConverged
+		}
+
+		p = next
+		if diff <= 1e-12*scale {
+			k, ok := riccatiGain(a, b, bt, r, p)
+			if !ok {
+				return linalg.Matrix{}, linalg.Matrix{}, ErrSingular
+			}
+			return k, p, nil
+		}
+	}
+
+	return linalg.Matrix{}, linalg.Matrix{}, ErrNotConverged
+}
+
+// return k = (r + b'*p*b)^-1 * b'*p*a
+func riccatiGain(a, b, bt, r, p linalg.Matrix) (linalg.Matrix, bool) {
+	btp := bt.Mul(p)
+	return r.Add(btp.Mul(b)).Solve(btp.Mul(a))
+}
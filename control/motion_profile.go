@@ -0,0 +1,230 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// =======
+// profile
+// =======
+
+// store one piece of a motion profile with constant jerk
+type profileSegment struct {
+	t0, dur    float64
+	p0, v0, a0 float64
+	j          float64
+}
+
+// store a motion profile as a chain of constant jerk segments
+type profile struct {
+	segs []profileSegment
+	end  float64
+	goal float64
+}
+
+func newProfile(start float64) profile {
+	return profile{goal: start, segs: []profileSegment{{p0: start}}}
+}
+
+// append a segment that starts with acceleration a0 and applies jerk j for dur seconds
+//
+// position and velocity continue from the end of the previous segment
+func (p *profile) push(dur, a0, j float64) {
+	if dur <= 0 {
+		return
+	}
+
+	last := p.segs[len(p.segs)-1]
+	pos, vel, _ := last.eval(last.dur)
+
+	p.segs = append(p.segs, profileSegment{t0: p.end, dur: dur, p0: pos, v0: vel, a0: a0, j: j})
+	p.end += dur
+}
+
+func (s profileSegment) eval(tau float64) (pos, vel, acc float64) {
+	pos = s.p0 + s.v0*tau + s.a0*tau*tau/2 + s.j*tau*tau*tau/6
+	vel = s.v0 + s.a0*tau + s.j*tau*tau/2
+	acc = s.a0 + s.j*tau
+	return pos, vel, acc
+}
+
+// return position, velocity and acceleration at time t from the profile start
+func (p profile) at(t float64) (pos, vel, acc float64) {
+	if t <= 0 {
+		return p.segs[0].p0, 0, 0
+	}
+	if t >= p.end {
+		return p.goal, 0, 0
+	}
+
+	for _, s := range p.segs[1:] {
+		if t < s.t0+s.dur {
+			return s.eval(t - s.t0)
+		}
+	}
+	return p.goal, 0, 0
+}
+
+// ===========
+// trapezoidal
+// ===========
+
+// generate a rest to rest move with a trapezoidal velocity profile
+//
+// acceleration is bang bang, velocity ramps up, cruises and ramps down
+type Trapezoidal[T c.Float] struct {
+	prof  profile
+	delay float64
+}
+
+// create a trapezoidal motion profile from start to goal
+//
+// vmax and amax limit the absolute velocity and acceleration
+//
+// the move begins at time delay
+//
+// if a limit is not positive, the profile jumps to goal at delay
+func NewTrapezoidal[T c.Float](start, goal, vmax, amax, delay T) Trapezoidal[T] {
+	p0, p1 := float64(start), float64(goal)
+	v, a := float64(vmax), float64(amax)
+
+	prof := newProfile(p0)
+	prof.goal = p1
+
+	d := math.Abs(p1 - p0)
+	if v > 0 && a > 0 && d > 0 {
+		s := math.Copysign(1, p1-p0)
+
+		ta := v / a
+		tv := d/v - ta
+		if tv < 0 {
+			// triangular profile, the velocity limit is never reached
+			ta = math.Sqrt(d / a)
+			tv = 0
+		}
+
+		prof.push(ta, s*a, 0)
+		prof.push(tv, 0, 0)
+		prof.push(ta, -s*a, 0)
+	}
+
+	return Trapezoidal[T]{prof: prof, delay: float64(delay)}
+}
+
+// compute the position at time t
+//
+// time: O(1)
+func (tr Trapezoidal[T]) Compute(t T) T {
+	pos, _, _ := tr.prof.at(float64(t) - tr.delay)
+	return T(pos)
+}
+
+// return position, velocity and acceleration at time t
+//
+// time: O(1)
+func (tr Trapezoidal[T]) At(t T) (pos, vel, acc T) {
+	p, v, a := tr.prof.at(float64(t) - tr.delay)
+	return T(p), T(v), T(a)
+}
+
+// return the duration of the move, excluding the delay
+//
+// time: O(1)
+func (tr Trapezoidal[T]) Duration() T {
+	return T(tr.prof.end)
+}
+
+// =======
+// s-curve
+// =======
+
+// generate a rest to rest move with a jerk limited seven segment profile
+//
+// acceleration ramps with bounded jerk, giving a smooth s shaped velocity
+type SCurve[T c.Float] struct {
+	prof  profile
+	delay float64
+}
+
+// create an s-curve motion profile from start to goal
+//
+// vmax, amax and jmax limit the absolute velocity, acceleration and jerk
+//
+// the move begins at time delay
+//
+// if a limit is not positive, the profile jumps to goal at delay
+func NewSCurve[T c.Float](start, goal, vmax, amax, jmax, delay T) SCurve[T] {
+	p0, p1 := float64(start), float64(goal)
+	v, a, j := float64(vmax), float64(amax), float64(jmax)
+
+	prof := newProfile(p0)
+	prof.goal = p1
+
+	d := math.Abs(p1 - p0)
+	if v > 0 && a > 0 && j > 0 && d > 0 {
+		s := math.Copysign(1, p1-p0)
+		tj, ta, tv := scurveTimes(d, v, a, j)
+		alim := j * tj
+
+		prof.push(tj, 0, s*j)
+		prof.push(ta-2*tj, s*alim, 0)
+		prof.push(tj, s*alim, -s*j)
+		prof.push(tv, 0, 0)
+		prof.push(tj, 0, -s*j)
+		prof.push(ta-2*tj, -s*alim, 0)
+		prof.push(tj, -s*alim, s*j)
+	}
+
+	return SCurve[T]{prof: prof, delay: float64(delay)}
+}
+
+// compute the position at time t
+//
+// time: O(1)
+func (sc SCurve[T]) Compute(t T) T {
+	pos, _, _ := sc.prof.at(float64(t) - sc.delay)
+	return T(pos)
+}
+
+// return position, velocity and acceleration at time t
+//
+// time: O(1)
+func (sc SCurve[T]) At(t T) (pos, vel, acc T) {
+	p, v, a := sc.prof.at(float64(t) - sc.delay)
+	return T(p), T(v), T(a)
+}
+
+// return the duration of the move, excluding the delay
+//
+// time: O(1)
+func (sc SCurve[T]) Duration() T {
+	return T(sc.prof.end)
+}
+
+// return the jerk time, the total acceleration time and the cruise time of a rest to rest s-curve over distance d
+func scurveTimes(d, v, a, j float64) (tj, ta, tv float64) {
+	if v*j >= a*a {
+		tj = a / j
+		ta = tj + v/a
+	} else {
+		// the acceleration limit is never reached
+		tj = math.Sqrt(v / j)
+		ta = 2 * tj
+	}
+
+	tv = d/v - ta
+	if tv >= 0 {
+		return tj, ta, tv
+	}
+
+	// the velocity limit is never reached
+	tj = a / j
+	ta = (a*a/j + math.Sqrt(a*a*a*a/(j*j)+4*a*d)) / (2 * a)
+	if ta < 2*tj {
+		tj = math.Cbrt(d / (2 * j))
+		ta = 2 * tj
+	}
+	return tj, ta, 0
+}
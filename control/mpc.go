@@ -0,0 +1,411 @@
+package control
+
+import (
+	"errors"
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// returned when the prediction horizon or the timestep are not positive
+var ErrHorizon = errors.New("control: horizon and timestep must be positive")
+
+// regulate a linear state space system with model predictive control
+//
+// at each step the input sequence u_0..u_{h-1} minimises
+//
+//	sum_{k=1}^{h} (x_k - ref)'*q*(x_k - ref) + sum_{k=0}^{h-1} u_k'*r*u_k + du_k'*s*du_k
+//
+// over the discretised model, where du_k = u_k - u_{k-1} and the last state is weighted
+// with the terminal weight instead of q
+//
+// inputs and input rates may be bounded, the resulting quadratic program is solved with a
+// dense admm solver warm started from the shifted previous solution, and u_0 is applied
+//
+// this type is not safe for concurrent use
+type Mpc[T c.Float] struct {
+	ad, bd     linalg.Matrix
+	n, m, h    int
+	dt         float64
+	q, r, s, p linalg.Matrix
+
+	uMin, uMax   []float64
+	duMin, duMax []float64
+
+	// quadratic program, rebuilt when the weights or the constraints change
+	dirty  bool
+	solver *qpSolver
+	fx, fr linalg.Matrix
+	box    bool
+	rate   bool
+	f      []float64
+	lo, hi []float64
+
+	x0, ref, prev []float64
+	out           []T
+
+	converged bool
+}
+
+// create a model predictive controller for sys sampled every dt over horizon steps
+//
+// q is the nxn state weight and r the mxm input weight, the terminal weight defaults to q
+//
+// return ErrDimension if the weights do not match the system and ErrHorizon if dt or horizon are not positive
+//
+// time: O((n+m)^3)
+func NewMpc[T c.Float](sys *StateSpace[T], dt T, horizon int, q, r [][]T) (*Mpc[T], error) {
+	if dt <= 0 || horizon < 1 {
+		return nil, ErrHorizon
+	}
+
+	n, m := sys.States(), sys.Inputs()
+	qm, ok := linalg.FromRows(q)
+	if !ok || qm.Rows != n || qm.Cols != n {
+		return nil, ErrDimension
+	}
+	rm, ok := linalg.FromRows(r)
+	if !ok || rm.Rows != m || rm.Cols != m {
+		return nil, ErrDimension
+	}
+
+	ad, bd := discretise(sys.a, sys.b, float64(dt), sys.method)
+
+	inf := func(v float64) []float64 {
+		out := make([]float64, m)
+		for i := range out {
+			out[i] = v
+		}
+		return out
+	}
+
+	return &Mpc[T]{
+		ad: ad, bd: bd,
+		n: n, m: m, h: horizon,
+		dt: float64(dt),
+		q:  qm, r: rm, s: linalg.New(m, m), p: qm.Clone(),
+		uMin: inf(math.Inf(-1)), uMax: inf(math.Inf(1)),
+		duMin: inf(math.Inf(-1)), duMax: inf(math.Inf(1)),
+		dirty: true,
+		x0:    make([]float64, n),
+		ref:   make([]float64, n),
+		prev:  make([]float64, m),
+		out:   make([]T, m),
+	}, nil
+}
+
+// set the weight on the input changes du_k, an mxm matrix
+//
+// return ErrDimension if s has the wrong shape
+//
+// time: O(m^2)
+func (mp *Mpc[T]) RateWeight(s [][]T) error {
+	sm, ok := linalg.FromRows(s)
+	if !ok || sm.Rows != mp.m || sm.Cols != mp.m {
+		return ErrDimension
+	}
+	mp.s = sm
+	mp.dirty = true
+	return nil
+}
+
+// set the weight on the last predicted state, an nxn matrix
+//
+// the cost matrix of Dlqr is a common choice
+//
+// return ErrDimension if p has the wrong shape
+//
+// time: O(n^2)
+func (mp *Mpc[T]) TerminalWeight(p [][]T) error {
+	pm, ok := linalg.FromRows(p)
+	if !ok || pm.Rows != mp.n || pm.Cols != mp.n {
+		return ErrDimension
+	}
+	mp.p = pm
+	mp.dirty = true
+	return nil
+}
+
+// bound every input channel to [min[i], max[i]]
+//
+// nil slices remove the bounds
+//
+// return ErrDimension if the slices do not have one entry per input
+//
+// time: O(m)
+func (mp *Mpc[T]) InputLimits(min, max []T) error {
+	return mp.limits(min, max, mp.uMin, mp.uMax, 1)
+}
+
+// bound the rate of change of every input channel to [min[i], max[i]] units per second
+//
+// nil slices remove the bounds
+//
+// return ErrDimension if the slices do not have one entry per input
+//
+// time: O(m)
+func (mp *Mpc[T]) RateLimits(min, max []T) error {
+	return mp.limits(min, max, mp.duMin, mp.duMax, mp.dt)
+}
+
+func (mp *Mpc[T]) limits(lo, hi []T, dstLo, dstHi []float64, scale float64) error {
+	if lo == nil && hi == nil {
+		for i := range dstLo {
+			dstLo[i], dstHi[i] = math.Inf(-1), math.Inf(1)
+		}
+		mp.dirty = true
+		return nil
+	}
+	if len(lo) != mp.m || len(hi) != mp.m {
+		return ErrDimension
+	}
+
+	for i := range dstLo {
+		a, b := float64(lo[i]), float64(hi[i])
+		dstLo[i], dstHi[i] = min(a, b)*scale, max(a, b)*scale
+	}
+	mp.dirty = true
+	return nil
+}
+
+// forget the warm start and the previous input
+//
+// time: O(h*m)
+func (mp *Mpc[T]) Reset() {
+	clear(mp.prev)
+	if mp.solver != nil {
+		mp.solver.reset()
+	}
+}
+
+// report whether the last quadratic program met the solver tolerance
+//
+// time: O(1)
+func (mp *Mpc[T]) Converged() bool {
+	return mp.converged
+}
+
+// return the input sequence predicted by the last compute call, one row per step
+//
+// time: O(h*m)
+func (mp *Mpc[T]) Plan() [][]T {
+	out := make([][]T, mp.h)
+	for k := range out {
+		out[k] = make([]T, mp.m)
+		if mp.solver == nil {
+			continue
+		}
+		for i := range mp.m {
+			out[k][i] = T(mp.solver.x[k*mp.m+i])
+		}
+	}
+	return out
+}
+
+// compute the inputs to apply at state x to drive it towards the reference state ref
+//
+// ref may be nil to regulate the state to zero
+//
+// the returned slice is owned by the controller and overwritten on the next call
+//
+// return zeros if x or ref do not have one entry per state
+//
+// time: O(iterations*h^2*m^2), plus O(h^3*(n+m)^3) after the weights or the constraints change
+func (mp *Mpc[T]) Compute(x, ref []T) []T {
+	if len(x) != mp.n || (ref != nil && len(ref) != mp.n) {
+		clear(mp.out)
+		return mp.out
+	}
+
+	if mp.dirty && !mp.build() {
+		mp.converged = false
+		clear(mp.out)
+		return mp.out
+	}
+
+	for i := range mp.n {
+		mp.x0[i] = float64(x[i])
+		mp.ref[i] = 0
+		if ref != nil {
+			mp.ref[i] = float64(ref[i])
+		}
+	}
+
+	// linear cost f = fx*x0 - fr*ref - 2*s*u_prev on the first step
+	nu := mp.h * mp.m
+	for i := range nu {
+		var acc float64
+		for j := range mp.n {
+			acc += mp.fx.Data[i*mp.n+j]*mp.x0[j] - mp.fr.Data[i*mp.n+j]*mp.ref[j]
+		}
+		mp.f[i] = acc
+	}
+	for i := range mp.m {
+		for j := range mp.m {
+			mp.f[i] -= 2 * mp.s.Data[i*mp.m+j] * mp.prev[j]
+		}
+	}
+
+	// the first rate bound is relative to the previous input
+	if mp.rate {
+		off := 0
+		if mp.box {
+			off = nu
+		}
+		for i := range mp.m {
+			mp.lo[off+i] = mp.prev[i] + mp.duMin[i]
+			mp.hi[off+i] = mp.prev[i] + mp.duMax[i]
+		}
+	}
+
+	mp.shift()
+	_, mp.converged = mp.solver.solve(mp.f, mp.lo, mp.hi)
+
+	// the solver may stop short of feasibility, so enforce the rate bound and then the input
+	// bounds, which take precedence if the limits changed and the two do not intersect
+	for i := range mp.m {
+		u := min(mp.prev[i]+mp.duMax[i], max(mp.prev[i]+mp.duMin[i], mp.solver.x[i]))
+		u = min(mp.uMax[i], max(mp.uMin[i], u))
+		mp.prev[i] = u
+		mp.out[i] = T(u)
+	}
+
+	return mp.out
+}
+
+// move the previous solution one step forward to warm start the next solve
+func (mp *Mpc[T]) shift() {
+	shiftBlocks(mp.solver.x, mp.m)
+
+	groups := 0
+	if mp.box {
+		groups++
+	}
+	if mp.rate {
+		groups++
+	}
+
+	nu := mp.h * mp.m
+	for g := range groups {
+		shiftBlocks(mp.solver.y[g*nu:(g+1)*nu], mp.m)
+	}
+}
+
+// drop the first block of size m and repeat the last one
+func shiftBlocks(v []float64, m int) {
+	if len(v) <= m {
+		return
+	}
+	copy(v, v[m:])
+}
+
+// assemble the condensed quadratic program for the current weights and constraints
+func (mp *Mpc[T]) build() bool {
+	n, m, h := mp.n, mp.m, mp.h
+	nu, nx := h*m, h*n
+
+	// predictions x = phi*x0 + gamma*u over the horizon
+	phi := linalg.New(nx, n)
+	gamma := linalg.New(nx, nu)
+
+	powers := make([]linalg.Matrix, h+1)
+	powers[0] = linalg.Identity(n)
+	for k := 1; k <= h; k++ {
+		powers[k] = mp.ad.Mul(powers[k-1])
+	}
+
+	for k := 1; k <= h; k++ {
+		setBlock(phi, (k-1)*n, 0, powers[k])
+		for i := range k {
+			setBlock(gamma, (k-1)*n, i*m, powers[k-1-i].Mul(mp.bd))
+		}
+	}
+
+	// block diagonal weights and the input difference operator
+	qbar := linalg.New(nx, nx)
+	stack := linalg.New(nx, n)
+	for k := range h {
+		w := mp.q
+		if k == h-1 {
+			w = mp.p
+		}
+		setBlock(qbar, k*n, k*n, w)
+		setBlock(stack, k*n, 0, linalg.Identity(n))
+	}
+
+	rbar := linalg.New(nu, nu)
+	sbar := linalg.New(nu, nu)
+	diff := linalg.New(nu, nu)
+	for k := range h {
+		setBlock(rbar, k*m, k*m, mp.r)
+		setBlock(sbar, k*m, k*m, mp.s)
+		setBlock(diff, k*m, k*m, linalg.Identity(m))
+		if k > 0 {
+			setBlock(diff, k*m, (k-1)*m, linalg.Identity(m).Scale(-1))
+		}
+	}
+
+	gtq := gamma.T().Mul(qbar)
+	hess := gtq.Mul(gamma).Add(rbar).Add(diff.T().Mul(sbar).Mul(diff)).Scale(2)
+	mp.fx = gtq.Mul(phi).Scale(2)
+	mp.fr = gtq.Mul(stack).Scale(2)
+
+	// stack the active constraint rows
+	mp.box, mp.rate = false, false
+	for i := range m {
+		mp.box = mp.box || !math.IsInf(mp.uMin[i], 0) || !math.IsInf(mp.uMax[i], 0)
+		mp.rate = mp.rate || !math.IsInf(mp.duMin[i], 0) || !math.IsInf(mp.duMax[i], 0)
+	}
+
+	rows := 0
+	if mp.box {
+		rows += nu
+	}
+	if mp.rate {
+		rows += nu
+	}
+
+	a := linalg.New(rows, nu)
+	mp.lo = make([]float64, rows)
+	mp.hi = make([]float64, rows)
+
+	off := 0
+	if mp.box {
+		setBlock(a, 0, 0, linalg.Identity(nu))
+		for k := range h {
+			copy(mp.lo[k*m:], mp.uMin)
+			copy(mp.hi[k*m:], mp.uMax)
+		}
+		off = nu
+	}
+	if mp.rate {
+		setBlock(a, off, 0, diff)
+		for k := range h {
+			copy(mp.lo[off+k*m:], mp.duMin)
+			copy(mp.hi[off+k*m:], mp.duMax)
+		}
+	}
+
+	solver, ok := newQpSolver(hess, a)
+	if !ok {
+		return false
+	}
+
+	// keep the previous plan as the warm start when only the problem data changed
+	if mp.solver != nil && len(mp.solver.x) == len(solver.x) {
+		copy(solver.x, mp.solver.x)
+	}
+
+	mp.solver = solver
+	mp.f = make([]float64, nu)
+	mp.dirty = false
+	return true
+}
+
+// copy block into m with its top left corner at row i and column j
+func setBlock(m linalg.Matrix, i, j int, block linalg.Matrix) {
+	for r := range block.Rows {
+		copy(m.Data[(i+r)*m.Cols+j:], block.Data[r*block.Cols:(r+1)*block.Cols])
+	}
+}
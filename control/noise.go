@@ -0,0 +1,131 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ===========
+// white noise
+// ===========
+
+// generate reproducible gaussian white noise
+//
+// a new sample is drawn every sample period and held in between
+//
+// the value at a given time only depends on the seed, so the signal can be evaluated in any order
+type WhiteNoise[T c.Float] struct {
+	std    float64
+	period float64
+	seed   uint64
+}
+
+// create a white noise reference
+//
+// std sets the standard deviation
+//
+// period sets the sample period in seconds
+//
+// seed selects the random stream
+func NewWhiteNoise[T c.Float](std, period T, seed uint64) WhiteNoise[T] {
+	return WhiteNoise[T]{std: float64(std), period: float64(period), seed: seed}
+}
+
+// compute the reference value at time t
+//
+// return 0 if the period is not positive
+//
+// time: O(1)
+func (n WhiteNoise[T]) Compute(t T) T {
+	if n.period <= 0 {
+		return 0
+	}
+	k := int64(math.Floor(float64(t) / n.period))
+	return T(n.std * gaussianAt(n.seed, uint64(k)))
+}
+
+// =============
+// colored noise
+// =============
+
+// generate reproducible first order colored noise
+//
+// samples follow the stationary ar(1) process
+//
+//	x_k = a*x_{k-1} + sqrt(1 - a^2)*std*w_k, with a = exp(-period/tau)
+//
+// the output is low pass filtered white noise with correlation time tau
+//
+// this type is not safe for concurrent use
+type ColoredNoise[T c.Float] struct {
+	std    float64
+	period float64
+	a      float64
+	seed   uint64
+
+	k    int64
+	x    float64
+	init bool
+}
+
+// create a colored noise reference
+//
+// std sets the stationary standard deviation
+//
+// tau sets the correlation time in seconds
+//
+// period sets the sample period in seconds
+//
+// seed selects the random stream
+func NewColoredNoise[T c.Float](std, tau, period T, seed uint64) *ColoredNoise[T] {
+	a := 0.0
+	if tau > 0 && period > 0 {
+		a = math.Exp(-float64(period) / float64(tau))
+	}
+	return &ColoredNoise[T]{std: float64(std), period: float64(period), a: a, seed: seed}
+}
+
+// compute the reference value at time t
+//
+// return 0 before time zero or if the period is not positive
+//
+// time: O(1) amortised for increasing t, O(t/period) when t moves backwards
+func (n *ColoredNoise[T]) Compute(t T) T {
+	if t < 0 || n.period <= 0 {
+		return 0
+	}
+
+	k := int64(math.Floor(float64(t) / n.period))
+	if !n.init || k < n.k {
+		n.k = 0
+		n.x = n.std * gaussianAt(n.seed, 0)
+		n.init = true
+	}
+
+	gain := math.Sqrt(1-n.a*n.a) * n.std
+	for n.k < k {
+		n.k++
+		n.x = n.a*n.x + gain*gaussianAt(n.seed, uint64(n.k))
+	}
+
+	return T(n.x)
+}
+
+// return a standard normal sample for index k of the stream selected by seed
+func gaussianAt(seed, k uint64) float64 {
+	h1 := splitmix64(seed ^ splitmix64(2*k))
+	h2 := splitmix64(seed ^ splitmix64(2*k+1))
+
+	// box muller with u1 in (0, 1]
+	u1 := float64(h1>>11+1) / (1 << 53)
+	u2 := float64(h2>>11) / (1 << 53)
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
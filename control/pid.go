@@ -4,18 +4,43 @@ import c "github.com/vistormu/go-dsa/constraints"
 
 // compute a pid control output from the current error signal
 //
+// the error form uses Compute(err, dt)
+//
+// the two degree of freedom form uses ComputeSp(sp, pv, dt) with setpoint weights b and c
+//
+//	u = kp*(b*sp - pv) + ki*integral(sp - pv) + kd*d/dt(c*sp - pv)
+//
 // this type is not safe for concurrent use
 type Pid[T c.Float] struct {
 	kp, ki, kd T
 	alpha      T
 
-	prevErr    T
+	// setpoint weights for the proportional and derivative terms
+	b, c T
+
+	prevD      T
 	integral   T
 	derivative T
+	lastP      T
+
+	// output offset that keeps gain changes bumpless while ki is zero
+	bias T
 
 	// integral clamp range for anti windup
 	iMin T
 	iMax T
+
+	// output saturation range
+	outMin T
+	outMax T
+
+	// back calculation tracking gain
+	kt T
+
+	conditional bool
+
+	manual    bool
+	manualOut T
 }
 
 // create a pid controller
@@ -26,14 +51,18 @@ type Pid[T c.Float] struct {
 //
 // alpha = 0 freezes the derivative at its previous value
 func NewPid[T c.Float](kp, ki, kd, alpha T) *Pid[T] {
-	return &Pid[T]{kp: kp, ki: ki, kd: kd, alpha: alpha}
+	return &Pid[T]{kp: kp, ki: ki, kd: kd, alpha: alpha, b: 1, c: 1}
 }
 
 // reset internal state
+//
+// the operating mode and configuration are kept
 func (p *Pid[T]) Reset() {
-	p.prevErr = 0
+	p.prevD = 0
 	p.integral = 0
 	p.derivative = 0
+	p.lastP = 0
+	p.bias = 0
 }
 
 // set integral clamp limits for anti windup
@@ -44,28 +73,213 @@ func (p *Pid[T]) AntiWindup(min, max T) {
 	p.iMax = max
 }
 
+// set output saturation limits
+//
+// if both min and max are zero, the output is not limited
+func (p *Pid[T]) OutputLimits(min, max T) {
+	p.outMin = min
+	p.outMax = max
+}
+
+// enable back calculation anti windup with tracking gain kt
+//
+// while the output saturates, the integral is driven towards the limit at rate kt*(u - v),
+// where v is the unsaturated and u the saturated output
+//
+// a common choice is kt = 1/sqrt(ti*td), or kt = 1/ti without derivative action
+//
+// kt = 0 disables back calculation
+//
+// only has effect when output limits are set
+func (p *Pid[T]) BackCalculation(kt T) {
+	p.kt = kt
+}
+
+// enable or disable conditional integration anti windup
+//
+// while the output saturates, integration is skipped if the error pushes further into the limit
+//
+// only has effect when output limits are set
+func (p *Pid[T]) ConditionalIntegration(on bool) {
+	p.conditional = on
+}
+
+// set the setpoint weights used by ComputeSp
+//
+// b weights the setpoint in the proportional term
+//
+// c weights the setpoint in the derivative term, c = 0 gives derivative on measurement
+//
+// both default to 1, which makes ComputeSp equivalent to Compute(sp - pv, dt)
+func (p *Pid[T]) SetpointWeights(b, c T) {
+	p.b = b
+	p.c = c
+}
+
+// return the current gains
+func (p *Pid[T]) Gains() (kp, ki, kd T) {
+	return p.kp, p.ki, p.kd
+}
+
+// change the gains at runtime without a bump in the output
+//
+// the output of the last compute call is kept by rescaling the integral, or by holding an
+// output bias while ki is zero
+//
+// the bias stays until ki is non zero again or the controller is reset
+//
+// the rescaled integral is clamped to the anti windup range, so gains that need an integral
+// outside it still step the output
+func (p *Pid[T]) SetGains(kp, ki, kd T) {
+	before := p.manualOut
+	if !p.manual {
+		before = p.saturate(p.output())
+	}
+
+	p.kp = kp
+	p.ki = ki
+	p.kd = kd
+	p.track(before)
+}
+
+// return the derivative smoothing factor
+func (p *Pid[T]) Alpha() T {
+	return p.alpha
+}
+
+// change the derivative smoothing factor in [0, 1] at runtime
+func (p *Pid[T]) SetAlpha(alpha T) {
+	p.alpha = alpha
+}
+
+// switch to manual mode with a fixed output
+//
+// while in manual mode, compute returns out and the integral, or the bias while ki is zero,
+// tracks it, so switching back to automatic mode is bumpless
+func (p *Pid[T]) SetManual(out T) {
+	p.manual = true
+	p.manualOut = out
+}
+
+// switch to automatic mode
+//
+// the integral, or the bias while ki is zero, is initialised so the first automatic output
+// continues from the manual one
+func (p *Pid[T]) SetAuto() {
+	if p.manual {
+		p.track(p.manualOut)
+	}
+	p.manual = false
+}
+
+// report whether the controller is in manual mode
+func (p *Pid[T]) Manual() bool {
+	return p.manual
+}
+
+// tell the controller that its last output was limited externally to out
+//
+// the integral, or the bias while ki is zero, is recomputed so the last output equals out,
+// which keeps it from winding up while something downstream saturates
+//
+// has no effect in manual mode
+//
+// time: O(1)
+func (p *Pid[T]) Track(out T) {
+	if !p.manual {
+		p.track(out)
+	}
+}
+
 // compute output given an error value and dt
 //
 // return 0 if dt is not positive
 //
 // time: O(1)
 func (p *Pid[T]) Compute(err, dt T) T {
+	return p.update(err, err, err, dt)
+}
+
+// compute output given a setpoint, a process value and dt
+//
+// the integral acts on sp - pv while the proportional and derivative terms use the setpoint weights
+//
+// return 0 if dt is not positive
+//
+// time: O(1)
+func (p *Pid[T]) ComputeSp(sp, pv, dt T) T {
+	return p.update(sp-pv, p.b*sp-pv, p.c*sp-pv, dt)
+}
+
+func (p *Pid[T]) update(e, ep, ed, dt T) T {
 	if dt <= 0 {
 		return 0
 	}
 
-	p.integral += err * dt
+	rawD := (ed - p.prevD) / dt
+	p.derivative = p.alpha*rawD + (1-p.alpha)*p.derivative
+	p.prevD = ed
+	p.lastP = ep
 
-	if !(p.iMin == 0 && p.iMax == 0) {
-		p.integral = min(p.iMax, max(p.iMin, p.integral))
+	if p.manual {
+		p.track(p.manualOut)
+		return p.manualOut
 	}
 
-	rawD := (err - p.prevErr) / dt
-	p.derivative = p.alpha*rawD + (1-p.alpha)*p.derivative
+	p.integral += e * dt
+	p.clampIntegral()
+
+	out := p.output()
+	sat := p.saturate(out)
+	if sat == out {
+		return out
+	}
+
+	// skip integration if the error drives the output further into saturation
+	if p.conditional && e*(out-sat) > 0 {
+		p.integral -= e * dt
+		p.clampIntegral()
+
+		out = p.output()
+		sat = p.saturate(out)
+	}
+
+	// bleed the integral towards the value that would just reach the limit
+	if p.kt != 0 && p.ki != 0 {
+		p.integral += p.kt * (sat - out) * dt / p.ki
+		p.clampIntegral()
+	}
 
-	out := p.kp*err + p.ki*p.integral + p.kd*p.derivative
+	return sat
+}
+
+// return the unsaturated output for the last proportional and derivative terms
+func (p *Pid[T]) output() T {
+	return p.kp*p.lastP + p.ki*p.integral + p.kd*p.derivative + p.bias
+}
 
-	p.prevErr = err
+// limit out to the output range, if one is set
+func (p *Pid[T]) saturate(out T) T {
+	if p.outMin == 0 && p.outMax == 0 {
+		return out
+	}
+	return min(p.outMax, max(p.outMin, out))
+}
 
-	return out
+// set the integral, or the bias while ki is zero, so that the controller output equals out
+func (p *Pid[T]) track(out T) {
+	rest := out - p.kp*p.lastP - p.kd*p.derivative
+	if p.ki == 0 {
+		p.bias = rest
+		return
+	}
+	p.integral = rest / p.ki
+	p.bias = 0
+	p.clampIntegral()
+}
+
+func (p *Pid[T]) clampIntegral() {
+	if !(p.iMin == 0 && p.iMax == 0) {
+		p.integral = min(p.iMax, max(p.iMin, p.integral))
+	}
 }
@@ -0,0 +1,116 @@
+package control
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+var (
+	// returned when the desired poles do not form a real polynomial of the system order
+	ErrPoles = errors.New("control: poles must be n values closed under conjugation")
+
+	// returned when the system cannot be steered by its input
+	ErrUncontrollable = errors.New("control: system is not controllable")
+)
+
+// compute the state feedback gain that places the closed loop poles with ackermann's formula
+//
+// for a single input system x' = a*x + b*u (or its discrete form) with a nxn and b nx1,
+// the control u = -k*x gives a - b*k the requested eigenvalues
+//
+//	k = [0 ... 0 1] * [b a*b ... a^(n-1)*b]^-1 * phi(a)
+//
+// where phi is the monic polynomial with roots poles
+//
+// use continuous poles with the continuous matrices and discrete poles with the discrete ones
+//
+// k is returned as a 1xn matrix so it can be passed to NewStateFeedback
+//
+// return ErrDimension if the shapes are inconsistent or b has more than one column,
+// ErrPoles if there are not n poles or complex poles lack their conjugate
+// and ErrUncontrollable if the controllability matrix is singular
+//
+// time: O(n^4)
+func Acker[T c.Float](a, b [][]T, poles []complex128) ([][]T, error) {
+	am, ok := linalg.FromRows(a)
+	if !ok || am.Rows != am.Cols {
+		return nil, ErrDimension
+	}
+	bm, ok := linalg.FromRows(b)
+	if !ok || bm.Rows != am.Rows || bm.Cols != 1 {
+		return nil, ErrDimension
+	}
+
+	n := am.Rows
+	if len(poles) != n {
+		return nil, ErrPoles
+	}
+
+	coef, ok := realPoly(poles)
+	if !ok {
+		return nil, ErrPoles
+	}
+
+	// controllability matrix [b a*b ... a^(n-1)*b]
+	ctrb := linalg.New(n, n)
+	col := bm
+	for j := range n {
+		for i := range n {
+			ctrb.Set(i, j, col.Data[i])
+		}
+		col = am.Mul(col)
+	}
+
+	// phi(a) by horner's rule
+	phi := linalg.Identity(n)
+	for _, ck := range coef[1:] {
+		phi = phi.Mul(am).Add(linalg.Identity(n).Scale(ck))
+	}
+
+	// k = e_n' * ctrb^-1 * phi = (ctrb'^-1 * e_n)' * phi
+	en := linalg.New(n, 1)
+	en.Data[n-1] = 1
+	y, ok := ctrb.T().Solve(en)
+	if !ok || !linalg.Finite(y.Data) || cond(ctrb) > 1e14 {
+		return nil, ErrUncontrollable
+	}
+
+	return linalg.ToRows[T](y.T().Mul(phi)), nil
+}
+
+// expand the monic polynomial with the given roots in descending powers
+//
+// return false if the coefficients are not real
+func realPoly(roots []complex128) ([]float64, bool) {
+	p := []complex128{1}
+	for _, r := range roots {
+		next := make([]complex128, len(p)+1)
+		for i, v := range p {
+			next[i] += v
+			next[i+1] -= v * r
+		}
+		p = next
+	}
+
+	out := make([]float64, len(p))
+	for i, v := range p {
+		if math.Abs(imag(v)) > 1e-9*max(1, cmplx.Abs(v)) {
+			return nil, false
+		}
+		out[i] = real(v)
+	}
+	return out, true
+}
+
+// estimate the infinity norm condition number of a square matrix
+func cond(m linalg.Matrix) float64 {
+	inv, ok := m.Inverse()
+	if !ok {
+		return math.Inf(1)
+	}
+	return m.NormInf() * inv.NormInf()
+}
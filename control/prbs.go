@@ -0,0 +1,92 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// maximal length feedback taps for orders 2 to 16
+var prbsTaps = [...][]uint{
+	2:  {2, 1},
+	3:  {3, 2},
+	4:  {4, 3},
+	5:  {5, 3},
+	6:  {6, 5},
+	7:  {7, 6},
+	8:  {8, 6, 5, 4},
+	9:  {9, 5},
+	10: {10, 7},
+	11: {11, 9},
+	12: {12, 11, 10, 4},
+	13: {13, 12, 11, 8},
+	14: {14, 13, 12, 2},
+	15: {15, 14},
+	16: {16, 15, 13, 4},
+}
+
+// generate a pseudo random binary sequence for system identification
+//
+// the sequence comes from a maximal length linear feedback shift register and repeats every 2^order - 1 bits
+type Prbs[T c.Float] struct {
+	amp       float64
+	bitPeriod float64
+	bits      []bool
+}
+
+// create a prbs reference switching between -amp and amp
+//
+// bitPeriod sets how long each bit is held in seconds
+//
+// order sets the register length and is clamped to [2, 16]
+//
+// seed sets the initial register state, a zero state is replaced by one
+//
+// time: O(2^order)
+func NewPrbs[T c.Float](amp, bitPeriod T, order int, seed uint64) Prbs[T] {
+	order = min(16, max(2, order))
+	n := uint(order)
+	mask := uint64(1)<<n - 1
+
+	state := seed & mask
+	if state == 0 {
+		state = 1
+	}
+
+	taps := prbsTaps[order]
+	bits := make([]bool, mask)
+	for i := range bits {
+		var fb uint64
+		for _, tap := range taps {
+			fb ^= state >> (n - tap)
+		}
+		bits[i] = state&1 == 1
+		state = (state >> 1) | (fb&1)<<(n-1)
+	}
+
+	return Prbs[T]{amp: float64(amp), bitPeriod: float64(bitPeriod), bits: bits}
+}
+
+// return the number of bits before the sequence repeats
+//
+// time: O(1)
+func (p Prbs[T]) Length() int {
+	return len(p.bits)
+}
+
+// compute the reference value at time t
+//
+// return 0 before time zero or if the bit period is not positive
+//
+// time: O(1)
+func (p Prbs[T]) Compute(t T) T {
+	if t < 0 || p.bitPeriod <= 0 || len(p.bits) == 0 {
+		return 0
+	}
+
+	k := int(math.Floor(float64(t)/p.bitPeriod)) % len(p.bits)
+	if p.bits[k] {
+		return T(p.amp)
+	}
+	return T(-p.amp)
+}
@@ -0,0 +1,44 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// generate a pulse width modulated reference signal
+type Pwm[T c.Float] struct {
+	amp    float64
+	freq   float64
+	duty   float64
+	offset float64
+}
+
+// create a pwm reference
+//
+// amp sets the pulse height above offset
+//
+// freq sets the frequency in hz
+//
+// duty sets the fraction of each period spent high, clamped to [0, 1]
+//
+// offset sets the low level
+func NewPwm[T c.Float](amp, freq, duty, offset T) Pwm[T] {
+	return Pwm[T]{
+		amp:    float64(amp),
+		freq:   float64(freq),
+		duty:   min(1, max(0, float64(duty))),
+		offset: float64(offset),
+	}
+}
+
+// compute the reference value at time t
+//
+// time: O(1)
+func (p Pwm[T]) Compute(t T) T {
+	x := p.freq * float64(t)
+	if x-math.Floor(x) < p.duty {
+		return T(p.amp + p.offset)
+	}
+	return T(p.offset)
+}
@@ -0,0 +1,172 @@
+package control
+
+import (
+	"math"
+
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// solve dense convex quadratic programs
+//
+//	minimise 1/2*x'*p*x + q'*x subject to l <= a*x <= u
+//
+// with the alternating direction method of multipliers, as in osqp, adapting the penalty rho
+// to balance the primal and dual residuals
+//
+// p and a are fixed at creation, q, l and u may change between solves
+//
+// the previous primal and dual iterates are kept, so consecutive solves warm start
+type qpSolver struct {
+	p, a, at linalg.Matrix
+
+	// (p + sigma*i + rho*a'*a)^-1, factored once
+	kinv linalg.Matrix
+
+	x, z, y []float64
+
+	// scratch buffers
+	rhs, xt, zt, ax, px, aty []float64
+
+	rho, sigma, alpha float64
+	tol               float64
+	maxIter           int
+}
+
+// create a solver for the cost matrix p and the constraint matrix a
+//
+// a may have zero rows for an unconstrained problem
+//
+// return false if the kkt system is singular
+func newQpSolver(p, a linalg.Matrix) (*qpSolver, bool) {
+	n, m := p.Rows, a.Rows
+
+	s := &qpSolver{
+		p: p, a: a, at: a.T(),
+		x: make([]float64, n), z: make([]float64, m), y: make([]float64, m),
+		rhs: make([]float64, n), xt: make([]float64, n), px: make([]float64, n), aty: make([]float64, n),
+		zt: make([]float64, m), ax: make([]float64, m),
+		rho: 0.1, sigma: 1e-6, alpha: 1.6,
+		tol:     1e-6,
+		maxIter: 4000,
+	}
+
+	if !s.factor() {
+		return nil, false
+	}
+	return s, true
+}
+
+// invert the kkt matrix p + sigma*i + rho*a'*a for the current rho
+func (s *qpSolver) factor() bool {
+	k := s.p.Add(linalg.Identity(s.p.Rows).Scale(s.sigma))
+	if s.a.Rows > 0 {
+		k = k.Add(s.at.Mul(s.a).Scale(s.rho))
+	}
+
+	kinv, ok := k.Inverse()
+	if !ok {
+		return false
+	}
+	s.kinv = kinv
+	return true
+}
+
+// solve the program for the linear cost q and bounds l and u, starting from the stored iterates
+//
+// return the number of iterations and whether the tolerance was met
+//
+// time: O(iterations*(n^2 + n*m)), plus O(n^3) each time rho is adapted
+func (s *qpSolver) solve(q, l, u []float64) (int, bool) {
+	n, m := len(s.x), len(s.z)
+
+	if m == 0 {
+		// unconstrained, the minimiser is -p^-1*q
+		for i := range n {
+			s.rhs[i] = -q[i]
+		}
+		s.kinv.MulVec(s.rhs, s.x)
+		return 1, true
+	}
+
+	// bring the warm start inside the bounds
+	s.a.MulVec(s.x, s.z)
+	for i := range m {
+		s.z[i] = min(u[i], max(l[i], s.z[i]))
+	}
+
+	for it := 1; it <= s.maxIter; it++ {
+		// x~ = kinv*(sigma*x - q + a'*(rho*z - y))
+		for i := range m {
+			s.zt[i] = s.rho*s.z[i] - s.y[i]
+		}
+		s.at.MulVec(s.zt, s.aty)
+		for i := range n {
+			s.rhs[i] = s.sigma*s.x[i] - q[i] + s.aty[i]
+		}
+		s.kinv.MulVec(s.rhs, s.xt)
+		s.a.MulVec(s.xt, s.zt)
+
+		// over relaxed updates of x, z and y
+		for i := range n {
+			s.x[i] = s.alpha*s.xt[i] + (1-s.alpha)*s.x[i]
+		}
+		for i := range m {
+			zr := s.alpha*s.zt[i] + (1-s.alpha)*s.z[i]
+			zn := min(u[i], max(l[i], zr+s.y[i]/s.rho))
+			s.y[i] += s.rho * (zr - zn)
+			s.z[i] = zn
+		}
+
+		if it%10 != 0 {
+			continue
+		}
+		rp, rd, ok := s.residuals(q)
+		if ok {
+			return it, true
+		}
+
+		// rebalance the primal and dual residuals, refactoring only on large changes
+		if it%50 == 0 && rp > 0 && rd > 0 {
+			rho := min(1e6, max(1e-6, s.rho*math.Sqrt(rp/rd)))
+			if rho > 5*s.rho || rho < s.rho/5 {
+				old := s.rho
+				s.rho = rho
+				if !s.factor() {
+					s.rho = old
+					s.factor()
+				}
+			}
+		}
+	}
+
+	_, _, ok := s.residuals(q)
+	return s.maxIter, ok
+}
+
+// return the scaled primal and dual residuals and whether both meet the tolerance
+func (s *qpSolver) residuals(q []float64) (float64, float64, bool) {
+	s.a.MulVec(s.x, s.ax)
+	s.p.MulVec(s.x, s.px)
+	s.at.MulVec(s.y, s.aty)
+
+	var rp, rd, scaleP, scaleD float64
+	for i, v := range s.ax {
+		rp = max(rp, math.Abs(v-s.z[i]))
+		scaleP = max(scaleP, math.Abs(v), math.Abs(s.z[i]))
+	}
+	for i, v := range s.px {
+		rd = max(rd, math.Abs(v+q[i]+s.aty[i]))
+		scaleD = max(scaleD, math.Abs(v), math.Abs(q[i]), math.Abs(s.aty[i]))
+	}
+
+	rp /= 1 + scaleP
+	rd /= 1 + scaleD
+	return rp, rd, rp <= s.tol && rd <= s.tol
+}
+
+// clear the stored iterates
+func (s *qpSolver) reset() {
+	clear(s.x)
+	clear(s.z)
+	clear(s.y)
+}
@@ -0,0 +1,309 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/system"
+)
+
+// return from a step function to end the loop without an error
+var ErrStop = errors.New("control: stop loop")
+
+// =====
+// clock
+// =====
+
+// source of time for a Runner
+type Clock interface {
+	// return the current time
+	Now() time.Time
+
+	// block until t or until ctx is done, returning ctx.Err() in that case
+	SleepUntil(ctx context.Context, t time.Time) error
+}
+
+// wall clock backed by the time package
+type SystemClock struct{}
+
+// return the current time
+//
+// time: O(1)
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// block until t or until ctx is done
+//
+// time: O(1)
+func (SystemClock) SleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deterministic clock for tests and simulations
+//
+// sleeping jumps straight to the wake up time, and Advance models time spent computing
+//
+// this type is safe for concurrent use
+type SimClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// create a simulated clock starting at start
+//
+// time: O(1)
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// return the simulated time
+//
+// time: O(1)
+func (s *SimClock) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// move the simulated time forward by d
+//
+// time: O(1)
+func (s *SimClock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.now = s.now.Add(d)
+	s.mu.Unlock()
+}
+
+// jump to t if it lies in the future, unless ctx is already done
+//
+// time: O(1)
+func (s *SimClock) SleepUntil(ctx context.Context, t time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if t.After(s.now) {
+		s.now = t
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// ==========
+// statistics
+// ==========
+
+// store timing statistics of a Runner
+type LoopStats struct {
+	// number of executed steps
+	Steps int
+
+	// number of steps that ended after the next deadline, the missed ticks are skipped
+	Overruns int
+
+	// smallest, largest and mean measured timestep
+	MinDt, MaxDt, MeanDt time.Duration
+
+	// largest deviation of the measured timestep from the period
+	MaxJitter time.Duration
+
+	// histogram of the deviation of the measured timestep from the period
+	//
+	// JitterCounts[i] counts deviations up to JitterEdges[i], the last entry counts the rest
+	JitterEdges  []time.Duration
+	JitterCounts []int
+}
+
+// default upper edges of the jitter histogram
+var defaultJitterEdges = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+}
+
+// ======
+// runner
+// ======
+
+// execute a step function at a fixed period
+//
+// deadlines are absolute multiples of the period from the start, so they do not drift,
+// and the step receives the measured time since the previous step in seconds
+//
+// this type is not safe for concurrent use
+type Runner[T c.Float] struct {
+	period time.Duration
+	step   func(dt T) error
+
+	clock    Clock
+	listener *system.SignalListener
+
+	stats  LoopStats
+	sumDt  time.Duration
+	nDt    int
+	edges  []time.Duration
+	counts []int
+}
+
+// create a runner that calls step every period
+//
+// the first step receives the nominal period as dt
+//
+// time: O(1)
+func NewRunner[T c.Float](period time.Duration, step func(dt T) error) *Runner[T] {
+	return &Runner[T]{
+		period: period,
+		step:   step,
+		clock:  SystemClock{},
+		edges:  defaultJitterEdges,
+	}
+}
+
+// use clk as the time source and return the runner for chaining
+//
+// time: O(1)
+func (r *Runner[T]) WithClock(clk Clock) *Runner[T] {
+	r.clock = clk
+	return r
+}
+
+// stop the loop cleanly when l delivers a signal and return the runner for chaining
+//
+// time: O(1)
+func (r *Runner[T]) WithSignals(l *system.SignalListener) *Runner[T] {
+	r.listener = l
+	return r
+}
+
+// set the upper edges of the jitter histogram in increasing order and return the runner for chaining
+//
+// time: O(n)
+func (r *Runner[T]) WithJitterEdges(edges ...time.Duration) *Runner[T] {
+	r.edges = append([]time.Duration(nil), edges...)
+	return r
+}
+
+// return a copy of the statistics gathered by the last run
+//
+// time: O(n) where n is the number of histogram edges
+func (r *Runner[T]) Stats() LoopStats {
+	s := r.stats
+	s.JitterEdges = append([]time.Duration(nil), r.edges...)
+	s.JitterCounts = append([]int(nil), r.counts...)
+	return s
+}
+
+// run the loop until ctx is done, a signal arrives or the step returns an error
+//
+// return nil on a clean stop, that is cancellation, a signal or ErrStop,
+// and the step error otherwise
+//
+// return nil without running if the period is not positive
+//
+// time: O(steps)
+func (r *Runner[T]) Run(ctx context.Context) error {
+	r.stats = LoopStats{}
+	r.sumDt, r.nDt = 0, 0
+	r.counts = make([]int, len(r.edges)+1)
+
+	if r.period <= 0 || r.step == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if r.listener != nil {
+		go func() {
+			select {
+			case _, ok := <-r.listener.Listen():
+				if ok {
+					cancel()
+				}
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	start := r.clock.Now()
+	next := start
+	prev := start
+	first := true
+
+	for {
+		if r.clock.SleepUntil(ctx, next) != nil {
+			return nil
+		}
+
+		now := r.clock.Now()
+		dt := r.period
+		if !first {
+			dt = now.Sub(prev)
+			r.record(dt)
+		}
+		prev, first = now, false
+
+		err := r.step(T(dt.Seconds()))
+		r.stats.Steps++
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// skip the ticks missed by a late step
+		next = next.Add(r.period)
+		if end := r.clock.Now(); end.After(next) {
+			r.stats.Overruns++
+			missed := end.Sub(next)/r.period + 1
+			next = next.Add(missed * r.period)
+		}
+	}
+}
+
+// accumulate a measured timestep into the statistics
+func (r *Runner[T]) record(dt time.Duration) {
+	if r.nDt == 0 || dt < r.stats.MinDt {
+		r.stats.MinDt = dt
+	}
+	r.stats.MaxDt = max(r.stats.MaxDt, dt)
+	r.sumDt += dt
+	r.nDt++
+	r.stats.MeanDt = r.sumDt / time.Duration(r.nDt)
+
+	jitter := dt - r.period
+	if jitter < 0 {
+		jitter = -jitter
+	}
+	r.stats.MaxJitter = max(r.stats.MaxJitter, jitter)
+
+	i := 0
+	for i < len(r.edges) && jitter > r.edges[i] {
+		i++
+	}
+	r.counts[i]++
+}
@@ -0,0 +1,42 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// generate a rising sawtooth reference signal
+type Sawtooth[T c.Float] struct {
+	amp    float64
+	freq   float64
+	phi    float64
+	offset float64
+}
+
+// create a sawtooth reference
+//
+// amp sets the amplitude, the output ramps from -amp to amp each period
+//
+// freq sets the frequency in hz
+//
+// phi sets the phase offset in radians
+//
+// offset adds a constant bias
+func NewSawtooth[T c.Float](amp, freq, phi, offset T) Sawtooth[T] {
+	return Sawtooth[T]{
+		amp:    float64(amp),
+		freq:   float64(freq),
+		phi:    float64(phi),
+		offset: float64(offset),
+	}
+}
+
+// compute the reference value at time t
+//
+// time: O(1)
+func (s Sawtooth[T]) Compute(t T) T {
+	x := s.freq*float64(t) + s.phi/(2*math.Pi)
+	frac := x - math.Floor(x)
+	return T(s.amp*(2*frac-1) + s.offset)
+}
@@ -1,6 +1,11 @@
 package control
 
-import c "github.com/vistormu/go-dsa/constraints"
+import (
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
 
 // simulate a second order siso lti system in state space form
 //
@@ -9,7 +14,7 @@ import c "github.com/vistormu/go-dsa/constraints"
 //	x' = a*x + b*u, where x is 2x1
 //	y  = c*x + d*u, where c is 1x2
 //
-// discretise with forward euler on each compute call
+// discretise with forward euler on each compute call unless another integrator is selected
 //
 // this type is not safe for concurrent use
 type SecondOrder[T c.Float] struct {
@@ -22,6 +27,13 @@ type SecondOrder[T c.Float] struct {
 	d      T
 
 	x1, x2 T
+
+	// discrete coefficients cached for the last dt
+	method     Integrator
+	dt         T
+	ad11, ad12 T
+	ad21, ad22 T
+	bd1, bd2   T
 }
 
 // create a second order system from state space coefficients
@@ -64,6 +76,15 @@ func NewSecondOrder[T c.Float](k, wn, zeta T) *SecondOrder[T] {
 	}
 }
 
+// select the integration scheme and return the system for chaining
+//
+// time: O(1)
+func (s *SecondOrder[T]) WithIntegrator(method Integrator) *SecondOrder[T] {
+	s.method = method
+	s.dt = 0
+	return s
+}
+
 // reset internal state
 //
 // time: O(1)
@@ -82,13 +103,54 @@ func (s *SecondOrder[T]) Compute(u, dt T) T {
 		return 0
 	}
 
-	// x' = a*x + b*u
-	dx1 := s.a11*s.x1 + s.a12*s.x2 + s.b1*u
-	dx2 := s.a21*s.x1 + s.a22*s.x2 + s.b2*u
+	if dt != s.dt {
+		s.discretise(dt)
+	}
 
-	// forward euler
-	s.x1 += dt * dx1
-	s.x2 += dt * dx2
+	// x_{k+1} = ad*x_k + bd*u_k
+	x1 := s.ad11*s.x1 + s.ad12*s.x2 + s.bd1*u
+	x2 := s.ad21*s.x1 + s.ad22*s.x2 + s.bd2*u
+	s.x1, s.x2 = x1, x2
 
 	return s.c1*s.x1 + s.c2*s.x2 + s.d*u
 }
+
+func (s *SecondOrder[T]) discretise(dt T) {
+	a := linalg.Matrix{Rows: 2, Cols: 2, Data: []float64{
+		float64(s.a11), float64(s.a12),
+		float64(s.a21), float64(s.a22),
+	}}
+	b := linalg.Matrix{Rows: 2, Cols: 1, Data: []float64{float64(s.b1), float64(s.b2)}}
+
+	ad, bd := discretise(a, b, float64(dt), s.method)
+
+	s.ad11, s.ad12 = T(ad.Data[0]), T(ad.Data[1])
+	s.ad21, s.ad22 = T(ad.Data[2]), T(ad.Data[3])
+	s.bd1, s.bd2 = T(bd.Data[0]), T(bd.Data[1])
+	s.dt = dt
+}
+
+// evaluate the continuous frequency response at w rad/s
+//
+//	g(jw) = c*(jw*i - a)^-1*b + d
+//
+// time: O(1)
+func (s *SecondOrder[T]) Freq(w float64) complex128 {
+	jw := complex(0, w)
+	m11 := jw - complex(float64(s.a11), 0)
+	m12 := complex(-float64(s.a12), 0)
+	m21 := complex(-float64(s.a21), 0)
+	m22 := jw - complex(float64(s.a22), 0)
+
+	det := m11*m22 - m12*m21
+	if det == 0 {
+		return cmplx.Inf()
+	}
+
+	// x = (jw*i - a)^-1*b through the 2x2 adjugate
+	b1, b2 := complex(float64(s.b1), 0), complex(float64(s.b2), 0)
+	x1 := (m22*b1 - m12*b2) / det
+	x2 := (m11*b2 - m21*b1) / det
+
+	return complex(float64(s.c1), 0)*x1 + complex(float64(s.c2), 0)*x2 + complex(float64(s.d), 0)
+}
@@ -0,0 +1,240 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// any reference signal evaluated at time t
+//
+// Step, Ramp, Sine, Square, Triangular and every generator and combinator in this package satisfy this interface
+type Signal[T c.Number] interface {
+	Compute(t T) T
+}
+
+// ========
+// constant
+// ========
+
+// produce a constant value
+type Constant[T c.Number] struct {
+	value T
+}
+
+// create a constant signal
+func NewConstant[T c.Number](value T) Constant[T] {
+	return Constant[T]{value: value}
+}
+
+// compute the reference value at time t
+//
+// time: O(1)
+func (k Constant[T]) Compute(t T) T {
+	return k.value
+}
+
+// ===
+// sum
+// ===
+
+// add several signals together
+type Sum[T c.Number] struct {
+	signals []Signal[T]
+}
+
+// create the sum of signals
+//
+// an empty sum is zero
+func NewSum[T c.Number](signals ...Signal[T]) Sum[T] {
+	return Sum[T]{signals: signals}
+}
+
+// compute the reference value at time t
+//
+// time: O(n) where n is the number of signals
+func (s Sum[T]) Compute(t T) T {
+	var out T
+	for _, sig := range s.signals {
+		out += sig.Compute(t)
+	}
+	return out
+}
+
+// =======
+// product
+// =======
+
+// multiply several signals together, useful for amplitude modulation
+type Product[T c.Number] struct {
+	signals []Signal[T]
+}
+
+// create the product of signals
+//
+// an empty product is one
+func NewProduct[T c.Number](signals ...Signal[T]) Product[T] {
+	return Product[T]{signals: signals}
+}
+
+// compute the reference value at time t
+//
+// time: O(n) where n is the number of signals
+func (p Product[T]) Compute(t T) T {
+	out := T(1)
+	for _, sig := range p.signals {
+		out *= sig.Compute(t)
+	}
+	return out
+}
+
+// =====
+// scale
+// =====
+
+// scale and offset a signal, y = gain*s(t) + offset
+type Scale[T c.Number] struct {
+	signal Signal[T]
+	gain   T
+	offset T
+}
+
+// create a scaled signal
+func NewScale[T c.Number](signal Signal[T], gain, offset T) Scale[T] {
+	return Scale[T]{signal: signal, gain: gain, offset: offset}
+}
+
+// compute the reference value at time t
+//
+// time: O(1) plus the cost of the wrapped signal
+func (s Scale[T]) Compute(t T) T {
+	return s.gain*s.signal.Compute(t) + s.offset
+}
+
+// =====
+// shift
+// =====
+
+// delay a signal in time, y = s(t - delay)
+type Shift[T c.Number] struct {
+	signal Signal[T]
+	delay  T
+}
+
+// create a time shifted signal
+//
+// a negative delay advances the signal
+func NewShift[T c.Number](signal Signal[T], delay T) Shift[T] {
+	return Shift[T]{signal: signal, delay: delay}
+}
+
+// compute the reference value at time t
+//
+// time: O(1) plus the cost of the wrapped signal
+func (s Shift[T]) Compute(t T) T {
+	return s.signal.Compute(t - s.delay)
+}
+
+// ====
+// clip
+// ====
+
+// clamp a signal to the inclusive range [lo, hi]
+type Clip[T c.Number] struct {
+	signal Signal[T]
+	lo, hi T
+}
+
+// create a clipped signal
+//
+// if lo is greater than hi, it swaps them
+func NewClip[T c.Number](signal Signal[T], lo, hi T) Clip[T] {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return Clip[T]{signal: signal, lo: lo, hi: hi}
+}
+
+// compute the reference value at time t
+//
+// time: O(1) plus the cost of the wrapped signal
+func (s Clip[T]) Compute(t T) T {
+	return min(s.hi, max(s.lo, s.signal.Compute(t)))
+}
+
+// ========
+// sequence
+// ========
+
+// describe one piece of a sequence
+type Segment[T c.Number] struct {
+	Signal   Signal[T]
+	Duration T
+}
+
+// play signals one after another
+//
+// each segment sees a local time that starts at zero when the segment begins
+//
+// before zero the first segment is used, after the last segment it keeps running
+type Sequence[T c.Number] struct {
+	segments []Segment[T]
+}
+
+// create a piecewise sequence of signals
+//
+// an empty sequence is zero
+func NewSequence[T c.Number](segments ...Segment[T]) Sequence[T] {
+	return Sequence[T]{segments: segments}
+}
+
+// compute the reference value at time t
+//
+// time: O(n) where n is the number of segments
+func (s Sequence[T]) Compute(t T) T {
+	if len(s.segments) == 0 {
+		return 0
+	}
+
+	var start T
+	for i, seg := range s.segments {
+		if t < start+seg.Duration || i == len(s.segments)-1 {
+			return seg.Signal.Compute(t - start)
+		}
+		start += seg.Duration
+	}
+	return 0
+}
+
+// ======
+// repeat
+// ======
+
+// repeat the first period of a signal forever
+type Repeat[T c.Number] struct {
+	signal Signal[T]
+	period T
+}
+
+// create a periodic repetition of signal over [0, period)
+//
+// if period is not positive, the signal is passed through unchanged
+func NewRepeat[T c.Number](signal Signal[T], period T) Repeat[T] {
+	return Repeat[T]{signal: signal, period: period}
+}
+
+// compute the reference value at time t
+//
+// time: O(1) plus the cost of the wrapped signal
+func (r Repeat[T]) Compute(t T) T {
+	if r.period <= 0 {
+		return r.signal.Compute(t)
+	}
+
+	p := float64(r.period)
+	local := math.Mod(float64(t), p)
+	if local < 0 {
+		local += p
+	}
+	return r.signal.Compute(T(local))
+}
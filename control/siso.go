@@ -0,0 +1,56 @@
+package control
+
+import c "github.com/vistormu/go-dsa/constraints"
+
+// simulate a single input single output state space system
+//
+// wrap a StateSpace to expose the scalar compute contract of FirstOrder and SecondOrder
+//
+// this type is not safe for concurrent use
+type Siso[T c.Float] struct {
+	ss *StateSpace[T]
+	u  []T
+}
+
+// create a siso system from a state space system with one input and one output
+//
+// return ErrDimension if ss is not siso
+//
+// time: O(1)
+func NewSiso[T c.Float](ss *StateSpace[T]) (*Siso[T], error) {
+	if ss == nil || ss.Inputs() != 1 || ss.Outputs() != 1 {
+		return nil, ErrDimension
+	}
+	return &Siso[T]{ss: ss, u: make([]T, 1)}, nil
+}
+
+// return the underlying state space system
+//
+// time: O(1)
+func (s *Siso[T]) StateSpace() *StateSpace[T] {
+	return s.ss
+}
+
+// reset internal state
+//
+// time: O(n)
+func (s *Siso[T]) Reset() {
+	s.ss.Reset()
+}
+
+// compute output for input u and timestep dt
+//
+// return 0 if dt is not positive
+//
+// time: O(n^2)
+func (s *Siso[T]) Compute(u, dt T) T {
+	s.u[0] = u
+	return s.ss.Compute(s.u, dt)[0]
+}
+
+// evaluate the continuous frequency response at w rad/s
+//
+// time: O(n^3)
+func (s *Siso[T]) Freq(w float64) complex128 {
+	return s.ss.Freq(w)
+}
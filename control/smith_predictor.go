@@ -0,0 +1,73 @@
+package control
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// compensate the dead time of a plant with a smith predictor around a pid
+//
+// an internal model, split into a delay free part and its dead time, predicts the output
+// the plant would have without delay, and the pid acts on
+//
+//	e' = e - (ym - ym_delayed)
+//
+// where ym is the delay free model output and ym_delayed the same output after the dead time
+//
+// with a perfect model the pid sees the delay free loop and can be tuned as such
+//
+// this type is not safe for concurrent use
+type SmithPredictor[T c.Float] struct {
+	pid   *Pid[T]
+	model Plant[T]
+	delay *Delay[T]
+
+	ym, ymd T
+}
+
+// create a smith predictor from a pid, a delay free model of the plant and the model dead time
+//
+// the model is stepped by the predictor and must not be shared with the plant
+func NewSmithPredictor[T c.Float](pid *Pid[T], model Plant[T], deadTime T) *SmithPredictor[T] {
+	return &SmithPredictor[T]{
+		pid:   pid,
+		model: model,
+		delay: NewDelay(deadTime),
+	}
+}
+
+// return the wrapped pid
+//
+// time: O(1)
+func (s *SmithPredictor[T]) Pid() *Pid[T] {
+	return s.pid
+}
+
+// reset the pid, the model when it has a Reset method and the dead time buffer
+//
+// time: O(n) where n is the number of buffered samples
+func (s *SmithPredictor[T]) Reset() {
+	s.pid.Reset()
+	if r, ok := s.model.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+	s.delay.Reset()
+	s.ym, s.ymd = 0, 0
+}
+
+// compute the control output for the measured error err = sp - pv and timestep dt
+//
+// return 0 if dt is not positive
+//
+// time: O(1) amortised plus the cost of the model
+func (s *SmithPredictor[T]) Compute(err, dt T) T {
+	if dt <= 0 {
+		return 0
+	}
+
+	u := s.pid.Compute(err-s.ym+s.ymd, dt)
+
+	s.ym = s.model.Compute(u, dt)
+	s.ymd = s.delay.Compute(s.ym, dt)
+
+	return u
+}
@@ -0,0 +1,301 @@
+package control
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vistormu/go-dsa/internal/snapshot"
+)
+
+// returned when a snapshot cannot be decoded or belongs to another type
+var ErrSnapshot = errors.New("control: invalid snapshot")
+
+// any block whose full configuration and internal state can be checkpointed and restored
+//
+// restoring a snapshot into a block of the same type resumes it exactly where the snapshot was taken
+//
+// Pid, FirstOrder and SecondOrder satisfy this interface, as do the stateful blocks of the filter package
+type Snapshotter interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// decode a binary snapshot of the given kind into state
+func decodeBinary(data []byte, kind string, state any) error {
+	if snapshot.Decode(data, kind, state) != nil {
+		return ErrSnapshot
+	}
+	return nil
+}
+
+// decode a json snapshot into state
+func decodeJSON(data []byte, state any) error {
+	if err := json.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshot, err)
+	}
+	return nil
+}
+
+// ===
+// pid
+// ===
+
+type pidState struct {
+	Kp         float64 `json:"kp"`
+	Ki         float64 `json:"ki"`
+	Kd         float64 `json:"kd"`
+	Alpha      float64 `json:"alpha"`
+	B          float64 `json:"b"`
+	C          float64 `json:"c"`
+	PrevD      float64 `json:"prev_d"`
+	Integral   float64 `json:"integral"`
+	Derivative float64 `json:"derivative"`
+	LastP      float64 `json:"last_p"`
+	IMin       float64 `json:"i_min"`
+	IMax       float64 `json:"i_max"`
+	OutMin     float64 `json:"out_min"`
+	OutMax     float64 `json:"out_max"`
+	Kt         float64 `json:"kt"`
+
+	Conditional bool    `json:"conditional"`
+	Manual      bool    `json:"manual"`
+	ManualOut   float64 `json:"manual_out"`
+	Bias        float64 `json:"bias"`
+}
+
+func (p *Pid[T]) state() pidState {
+	return pidState{
+		Kp: float64(p.kp), Ki: float64(p.ki), Kd: float64(p.kd), Alpha: float64(p.alpha),
+		B: float64(p.b), C: float64(p.c),
+		PrevD: float64(p.prevD), Integral: float64(p.integral), Derivative: float64(p.derivative), LastP: float64(p.lastP),
+		IMin: float64(p.iMin), IMax: float64(p.iMax),
+		OutMin: float64(p.outMin), OutMax: float64(p.outMax),
+		Kt:          float64(p.kt),
+		Conditional: p.conditional,
+		Manual:      p.manual, ManualOut: float64(p.manualOut), Bias: float64(p.bias),
+	}
+}
+
+func (p *Pid[T]) restore(s pidState) {
+	*p = Pid[T]{
+		kp: T(s.Kp), ki: T(s.Ki), kd: T(s.Kd), alpha: T(s.Alpha),
+		b: T(s.B), c: T(s.C),
+		prevD: T(s.PrevD), integral: T(s.Integral), derivative: T(s.Derivative), lastP: T(s.LastP),
+		iMin: T(s.IMin), iMax: T(s.IMax),
+		outMin: T(s.OutMin), outMax: T(s.OutMax),
+		kt:          T(s.Kt),
+		conditional: s.Conditional,
+		manual:      s.Manual, manualOut: T(s.ManualOut), bias: T(s.Bias),
+	}
+}
+
+// encode the gains, settings and internal state in a compact binary form
+//
+// time: O(1)
+func (p *Pid[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("pid", p.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid pid snapshot, leaving the controller unchanged
+//
+// time: O(1)
+func (p *Pid[T]) UnmarshalBinary(data []byte) error {
+	var s pidState
+	if err := decodeBinary(data, "pid", &s); err != nil {
+		return err
+	}
+	p.restore(s)
+	return nil
+}
+
+// encode the gains, settings and internal state as json
+//
+// time: O(1)
+func (p *Pid[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not valid json, leaving the controller unchanged
+//
+// time: O(1)
+func (p *Pid[T]) UnmarshalJSON(data []byte) error {
+	var s pidState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	p.restore(s)
+	return nil
+}
+
+// ============
+// first order
+// ============
+
+type firstOrderState struct {
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	C      float64 `json:"c"`
+	D      float64 `json:"d"`
+	X      float64 `json:"x"`
+	Method int     `json:"method"`
+}
+
+func (s *FirstOrder[T]) state() firstOrderState {
+	return firstOrderState{
+		A: float64(s.a), B: float64(s.b), C: float64(s.c), D: float64(s.d),
+		X:      float64(s.x),
+		Method: int(s.method),
+	}
+}
+
+func (s *FirstOrder[T]) restore(st firstOrderState) error {
+	if !validIntegrator(st.Method) {
+		return ErrSnapshot
+	}
+	*s = FirstOrder[T]{
+		a: T(st.A), b: T(st.B), c: T(st.C), d: T(st.D),
+		x:      T(st.X),
+		method: Integrator(st.Method),
+	}
+	return nil
+}
+
+// encode the coefficients, integrator and state in a compact binary form
+//
+// time: O(1)
+func (s *FirstOrder[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("first_order", s.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid first order snapshot, leaving the system unchanged
+//
+// time: O(1)
+func (s *FirstOrder[T]) UnmarshalBinary(data []byte) error {
+	var st firstOrderState
+	if err := decodeBinary(data, "first_order", &st); err != nil {
+		return err
+	}
+	return s.restore(st)
+}
+
+// encode the coefficients, integrator and state as json
+//
+// time: O(1)
+func (s *FirstOrder[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not a valid first order snapshot, leaving the system unchanged
+//
+// time: O(1)
+func (s *FirstOrder[T]) UnmarshalJSON(data []byte) error {
+	var st firstOrderState
+	if err := decodeJSON(data, &st); err != nil {
+		return err
+	}
+	return s.restore(st)
+}
+
+// ============
+// second order
+// ============
+
+type secondOrderState struct {
+	A      []float64 `json:"a"`
+	B      []float64 `json:"b"`
+	C      []float64 `json:"c"`
+	D      float64   `json:"d"`
+	X      []float64 `json:"x"`
+	Method int       `json:"method"`
+}
+
+func (s *SecondOrder[T]) state() secondOrderState {
+	f := func(v ...T) []float64 {
+		out := make([]float64, len(v))
+		for i, x := range v {
+			out[i] = float64(x)
+		}
+		return out
+	}
+	return secondOrderState{
+		A:      f(s.a11, s.a12, s.a21, s.a22),
+		B:      f(s.b1, s.b2),
+		C:      f(s.c1, s.c2),
+		D:      float64(s.d),
+		X:      f(s.x1, s.x2),
+		Method: int(s.method),
+	}
+}
+
+func (s *SecondOrder[T]) restore(st secondOrderState) error {
+	if len(st.A) != 4 || len(st.B) != 2 || len(st.C) != 2 || len(st.X) != 2 || !validIntegrator(st.Method) {
+		return ErrSnapshot
+	}
+	*s = SecondOrder[T]{
+		a11: T(st.A[0]), a12: T(st.A[1]),
+		a21: T(st.A[2]), a22: T(st.A[3]),
+		b1: T(st.B[0]), b2: T(st.B[1]),
+		c1: T(st.C[0]), c2: T(st.C[1]),
+		d:  T(st.D),
+		x1: T(st.X[0]), x2: T(st.X[1]),
+		method: Integrator(st.Method),
+	}
+	return nil
+}
+
+// encode the coefficients, integrator and state in a compact binary form
+//
+// time: O(1)
+func (s *SecondOrder[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("second_order", s.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid second order snapshot, leaving the system unchanged
+//
+// time: O(1)
+func (s *SecondOrder[T]) UnmarshalBinary(data []byte) error {
+	var st secondOrderState
+	if err := decodeBinary(data, "second_order", &st); err != nil {
+		return err
+	}
+	return s.restore(st)
+}
+
+// encode the coefficients, integrator and state as json
+//
+// time: O(1)
+func (s *SecondOrder[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not a valid second order snapshot, leaving the system unchanged
+//
+// time: O(1)
+func (s *SecondOrder[T]) UnmarshalJSON(data []byte) error {
+	var st secondOrderState
+	if err := decodeJSON(data, &st); err != nil {
+		return err
+	}
+	return s.restore(st)
+}
+
+// report whether m names a known integrator
+func validIntegrator(m int) bool {
+	return m >= int(ForwardEuler) && m <= int(ZOH)
+}
@@ -0,0 +1,106 @@
+package control
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// regulate a state space system with the static control law u = k*(ref - x)
+//
+// k is an mxn gain, for instance from Dlqr or Acker
+//
+// this type is not safe for concurrent use
+type StateFeedback[T c.Float] struct {
+	k linalg.Matrix
+
+	e []float64
+	u []T
+
+	outMin, outMax T
+}
+
+// create a state feedback controller from an mxn gain matrix
+//
+// return ErrDimension if the rows are empty or have different lengths
+//
+// time: O(m*n)
+func NewStateFeedback[T c.Float](k [][]T) (*StateFeedback[T], error) {
+	km, ok := linalg.FromRows(k)
+	if !ok {
+		return nil, ErrDimension
+	}
+
+	return &StateFeedback[T]{
+		k: km,
+		e: make([]float64, km.Cols),
+		u: make([]T, km.Rows),
+	}, nil
+}
+
+// return a copy of the gain matrix
+//
+// time: O(m*n)
+func (s *StateFeedback[T]) Gain() [][]T {
+	return linalg.ToRows[T](s.k)
+}
+
+// replace the gain matrix, keeping its shape
+//
+// return ErrDimension if k does not have the shape of the current gain
+//
+// time: O(m*n)
+func (s *StateFeedback[T]) SetGain(k [][]T) error {
+	km, ok := linalg.FromRows(k)
+	if !ok || km.Rows != s.k.Rows || km.Cols != s.k.Cols {
+		return ErrDimension
+	}
+	s.k = km
+	return nil
+}
+
+// set output saturation limits applied to every input channel
+//
+// if both min and max are zero, the output is not limited
+func (s *StateFeedback[T]) OutputLimits(min, max T) {
+	s.outMin = min
+	s.outMax = max
+}
+
+// compute the control inputs for state x and reference state ref
+//
+// ref may be nil to regulate the state to zero
+//
+// the returned slice is owned by the controller and overwritten on the next call
+//
+// return zeros if x or ref do not have one entry per state
+//
+// time: O(m*n)
+func (s *StateFeedback[T]) Compute(x, ref []T) []T {
+	if len(x) != len(s.e) || (ref != nil && len(ref) != len(s.e)) {
+		clear(s.u)
+		return s.u
+	}
+
+	for i, xi := range x {
+		s.e[i] = -float64(xi)
+		if ref != nil {
+			s.e[i] += float64(ref[i])
+		}
+	}
+
+	n := len(s.e)
+	for i := range s.u {
+		var acc float64
+		for j, ej := range s.e {
+			acc += s.k.Data[i*n+j] * ej
+		}
+
+		u := T(acc)
+		if s.outMin != 0 || s.outMax != 0 {
+			u = min(s.outMax, max(s.outMin, u))
+		}
+		s.u[i] = u
+	}
+
+	return s.u
+}
@@ -0,0 +1,251 @@
+package control
+
+import (
+	"errors"
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// returned when system matrices have inconsistent shapes
+var ErrDimension = errors.New("control: inconsistent matrix dimensions")
+
+// simulate an n state mimo lti system in state space form
+//
+// continuous model
+//
+//	x' = a*x + b*u, where a is nxn and b is nxm
+//	y  = c*x + d*u, where c is pxn and d is pxm
+//
+// discretise with forward euler on each compute call unless another integrator is selected
+//
+// this type is not safe for concurrent use
+type StateSpace[T c.Float] struct {
+	a, b, c, d linalg.Matrix
+
+	x  []float64
+	xn []float64
+	u  []float64
+	y  []T
+
+	// discrete matrices cached for the last dt
+	method Integrator
+	dt     T
+	ad, bd linalg.Matrix
+}
+
+// create a state space system from its matrices
+//
+// d may be nil, in which case it is treated as zero
+//
+// return ErrDimension if the shapes are inconsistent
+//
+// time: O(n^2 + n*m + p*n + p*m)
+func NewStateSpace[T c.Float](a, b, cm, d [][]T) (*StateSpace[T], error) {
+	am, ok := linalg.FromRows(a)
+	if !ok || am.Rows != am.Cols {
+		return nil, ErrDimension
+	}
+	bm, ok := linalg.FromRows(b)
+	if !ok || bm.Rows != am.Rows {
+		return nil, ErrDimension
+	}
+	cmm, ok := linalg.FromRows(cm)
+	if !ok || cmm.Cols != am.Rows {
+		return nil, ErrDimension
+	}
+
+	dm := linalg.New(cmm.Rows, bm.Cols)
+	if d != nil {
+		dm, ok = linalg.FromRows(d)
+		if !ok || dm.Rows != cmm.Rows || dm.Cols != bm.Cols {
+			return nil, ErrDimension
+		}
+	}
+
+	return newStateSpace[T](am, bm, cmm, dm), nil
+}
+
+func newStateSpace[T c.Float](a, b, cm, d linalg.Matrix) *StateSpace[T] {
+	return &StateSpace[T]{
+		a: a, b: b, c: cm, d: d,
+		x:  make([]float64, a.Rows),
+		xn: make([]float64, a.Rows),
+		u:  make([]float64, b.Cols),
+		y:  make([]T, cm.Rows),
+	}
+}
+
+// select the integration scheme and return the system for chaining
+//
+// time: O(1)
+func (s *StateSpace[T]) WithIntegrator(method Integrator) *StateSpace[T] {
+	s.method = method
+	s.dt = 0
+	return s
+}
+
+// return the number of states
+//
+// time: O(1)
+func (s *StateSpace[T]) States() int {
+	return s.a.Rows
+}
+
+// return the number of inputs
+//
+// time: O(1)
+func (s *StateSpace[T]) Inputs() int {
+	return s.b.Cols
+}
+
+// return the number of outputs
+//
+// time: O(1)
+func (s *StateSpace[T]) Outputs() int {
+	return s.c.Rows
+}
+
+// return copies of the continuous a, b, c and d matrices
+//
+// time: O(n^2 + n*m + p*n + p*m)
+func (s *StateSpace[T]) Matrices() (a, b, cm, d [][]T) {
+	return linalg.ToRows[T](s.a), linalg.ToRows[T](s.b), linalg.ToRows[T](s.c), linalg.ToRows[T](s.d)
+}
+
+// return the discrete a and b matrices for timestep dt with the selected integrator
+//
+// useful to design discrete controllers such as Dlqr for this system
+//
+// return nil matrices if dt is not positive
+//
+// time: O((n+m)^3)
+func (s *StateSpace[T]) Discrete(dt T) (ad, bd [][]T) {
+	if dt <= 0 {
+		return nil, nil
+	}
+	a, b := discretise(s.a, s.b, float64(dt), s.method)
+	return linalg.ToRows[T](a), linalg.ToRows[T](b)
+}
+
+// return a copy of the current state vector
+//
+// time: O(n)
+func (s *StateSpace[T]) State() []T {
+	out := make([]T, len(s.x))
+	for i, v := range s.x {
+		out[i] = T(v)
+	}
+	return out
+}
+
+// overwrite the current state vector
+//
+// return false if x does not have one entry per state
+//
+// time: O(n)
+func (s *StateSpace[T]) SetState(x []T) bool {
+	if len(x) != len(s.x) {
+		return false
+	}
+	for i, v := range x {
+		s.x[i] = float64(v)
+	}
+	return true
+}
+
+// reset internal state
+//
+// time: O(n)
+func (s *StateSpace[T]) Reset() {
+	clear(s.x)
+}
+
+// compute the outputs for inputs u and timestep dt
+//
+// the returned slice is owned by the system and overwritten on the next call
+//
+// return zeros if dt is not positive or u does not have one entry per input
+//
+// time: O(n^2 + n*m + p*n + p*m), plus O((n+m)^3) when dt changes
+func (s *StateSpace[T]) Compute(u []T, dt T) []T {
+	if dt <= 0 || len(u) != len(s.u) {
+		clear(s.y)
+		return s.y
+	}
+
+	if dt != s.dt {
+		s.ad, s.bd = discretise(s.a, s.b, float64(dt), s.method)
+		s.dt = dt
+	}
+
+	for i, v := range u {
+		s.u[i] = float64(v)
+	}
+
+	// x_{k+1} = ad*x_k + bd*u_k
+	n := len(s.x)
+	for i := range n {
+		var acc float64
+		for j := range n {
+			acc += s.ad.Data[i*n+j] * s.x[j]
+		}
+		for j, uj := range s.u {
+			acc += s.bd.Data[i*len(s.u)+j] * uj
+		}
+		s.xn[i] = acc
+	}
+	s.x, s.xn = s.xn, s.x
+
+	// y = c*x + d*u
+	for i := range s.y {
+		var acc float64
+		for j, xj := range s.x {
+			acc += s.c.Data[i*n+j] * xj
+		}
+		for j, uj := range s.u {
+			acc += s.d.Data[i*len(s.u)+j] * uj
+		}
+		s.y[i] = T(acc)
+	}
+
+	return s.y
+}
+
+// evaluate the continuous frequency response from input in to output out at w rad/s
+//
+//	g(jw) = c*(jw*i - a)^-1*b + d
+//
+// return 0 if the channel does not exist
+//
+// time: O(n^3)
+func (s *StateSpace[T]) FreqIO(w float64, out, in int) complex128 {
+	if out < 0 || out >= s.c.Rows || in < 0 || in >= s.b.Cols {
+		return 0
+	}
+
+	n := s.a.Rows
+	col := make([]float64, n)
+	for i := range n {
+		col[i] = s.b.At(i, in)
+	}
+
+	x, ok := linalg.Resolvent(s.a, complex(0, w), col)
+	if !ok {
+		return cmplx.Inf()
+	}
+
+	g := complex(s.d.At(out, in), 0)
+	for i, xi := range x {
+		g += complex(s.c.At(out, i), 0) * xi
+	}
+	return g
+}
+
+// evaluate the continuous frequency response from the first input to the first output
+//
+// time: O(n^3)
+func (s *StateSpace[T]) Freq(w float64) complex128 {
+	return s.FreqIO(w, 0, 0)
+}
@@ -0,0 +1,218 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// any feedback controller driven by an error signal and a timestep
+//
+// Pid satisfies this interface
+type Controller[T c.Float] interface {
+	Compute(err, dt T) T
+}
+
+// store the samples recorded while simulating a closed loop
+type LoopTrace[T c.Float] struct {
+	// sample times
+	T []T
+
+	// reference, plant output and control input at each sample
+	R []T
+	Y []T
+	U []T
+}
+
+// store standard step response metrics
+//
+// times are measured from the step instant
+type StepMetrics[T c.Float] struct {
+	// time to go from 10% to 90% of the step
+	RiseTime T
+
+	// time of the largest excursion towards the target
+	PeakTime T
+
+	// largest output value in the step direction
+	Peak T
+
+	// overshoot past the target as a percentage of the step size
+	Overshoot T
+
+	// time after which the output stays within the band around the target
+	SettlingTime T
+
+	// target minus the final output
+	SteadyStateError T
+
+	// integral of absolute error
+	IAE T
+
+	// integral of squared error
+	ISE T
+
+	// integral of time weighted absolute error
+	ITAE T
+}
+
+// simulate a unity feedback loop from rest
+//
+// at each sample the error r - y drives the controller and its output drives the plant
+//
+// return an empty trace if dt or duration are not positive
+//
+// time: O(duration/dt)
+func SimulateLoop[T c.Float](
+	ctrl Controller[T],
+	plant Plant[T],
+	ref Signal[T],
+	duration, dt T,
+) LoopTrace[T] {
+	if dt <= 0 || duration <= 0 {
+		return LoopTrace[T]{}
+	}
+
+	n := int(math.Round(float64(duration/dt))) + 1
+	tr := LoopTrace[T]{
+		T: make([]T, n),
+		R: make([]T, n),
+		Y: make([]T, n),
+		U: make([]T, n),
+	}
+
+	var y T
+	for i := range n {
+		t := T(i) * dt
+		r := ref.Compute(t)
+
+		tr.T[i] = t
+		tr.R[i] = r
+		tr.Y[i] = y
+
+		if i == n-1 {
+			break
+		}
+
+		u := ctrl.Compute(r-y, dt)
+		tr.U[i] = u
+		y = plant.Compute(u, dt)
+	}
+	tr.U[n-1] = tr.U[max(0, n-2)]
+
+	return tr
+}
+
+// compute step response metrics from sampled output y at times t
+//
+// the step to target is applied at time start, the initial value is the last sample before it
+//
+// band is the settling tolerance as a fraction of the step size, 0.02 if not positive
+//
+// return zero metrics if the samples are empty or the step size is zero
+//
+// time: O(n)
+func StepResponse[T c.Float](t, y []T, start, target, band T) StepMetrics[T] {
+	if len(t) == 0 || len(t) != len(y) {
+		return StepMetrics[T]{}
+	}
+	if band <= 0 {
+		band = 0.02
+	}
+
+	// locate the step instant and the initial value
+	first := 0
+	for first < len(t) && t[first] < start {
+		first++
+	}
+	if first == len(t) {
+		return StepMetrics[T]{}
+	}
+
+	y0 := y[max(0, first-1)]
+
+	step := float64(target - y0)
+	if step == 0 {
+		return StepMetrics[T]{}
+	}
+	dir := math.Copysign(1, step)
+
+	var m StepMetrics[T]
+
+	// rise time between the 10% and 90% crossings
+	t10, t90 := -1.0, -1.0
+	peak := math.Inf(-1)
+	for i := first; i < len(t); i++ {
+		frac := float64(y[i]-y0) / step
+		ti := float64(t[i] - start)
+
+		if t10 < 0 && frac >= 0.1 {
+			t10 = crossing(t, y, i, float64(y0)+0.1*step) - float64(start)
+		}
+		if t90 < 0 && frac >= 0.9 {
+			t90 = crossing(t, y, i, float64(y0)+0.9*step) - float64(start)
+		}
+
+		if v := dir * float64(y[i]); v > peak {
+			peak = v
+			m.Peak = y[i]
+			m.PeakTime = T(ti)
+		}
+	}
+	if t10 >= 0 && t90 >= 0 {
+		m.RiseTime = T(t90 - t10)
+	}
+
+	m.Overshoot = T(max(0, dir*float64(m.Peak-target)/math.Abs(step)*100))
+
+	// settling time from the last sample outside the band
+	tol := float64(band) * math.Abs(step)
+	for i := len(t) - 1; i >= first; i-- {
+		if math.Abs(float64(y[i]-target)) > tol {
+			if i+1 < len(t) {
+				m.SettlingTime = t[i+1] - start
+			} else {
+				m.SettlingTime = T(math.Inf(1))
+			}
+			break
+		}
+	}
+
+	m.SteadyStateError = target - y[len(y)-1]
+
+	// error integrals with the rectangle rule
+	var iae, ise, itae float64
+	for i := first; i < len(t)-1; i++ {
+		h := float64(t[i+1] - t[i])
+		e := float64(target - y[i])
+		iae += math.Abs(e) * h
+		ise += e * e * h
+		itae += float64(t[i]-start) * math.Abs(e) * h
+	}
+	m.IAE, m.ISE, m.ITAE = T(iae), T(ise), T(itae)
+
+	return m
+}
+
+// simulate a unity feedback loop against a step reference and score the response
+//
+// band is the settling tolerance as a fraction of the step size, 0.02 if not positive
+//
+// time: O(duration/dt)
+func SimulateStep[T c.Float](ctrl Controller[T], plant Plant[T], step Step[T], duration, dt, band T) (LoopTrace[T], StepMetrics[T]) {
+	tr := SimulateLoop(ctrl, plant, step, duration, dt)
+	return tr, StepResponse(tr.T, tr.Y, step.delay, step.amp, band)
+}
+
+// interpolate the time at which y crosses level between samples i-1 and i
+func crossing[T c.Float](t, y []T, i int, level float64) float64 {
+	if i == 0 {
+		return float64(t[0])
+	}
+	a, b := float64(y[i-1]), float64(y[i])
+	ta, tb := float64(t[i-1]), float64(t[i])
+	if a == b {
+		return tb
+	}
+	return ta + (level-a)/(b-a)*(tb-ta)
+}
@@ -0,0 +1,198 @@
+package control
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// track a moving goal with velocity, acceleration and optional jerk limits
+//
+// the online counterpart of Trapezoidal and SCurve, the goal can change at any step
+// and the motion is replanned from the current position, velocity and acceleration
+//
+// each step follows the braking curve of the limits, so moves are near time optimal
+// and never need more than the limits to stop at the goal
+//
+// this type is not safe for concurrent use
+type OnlineTrajectory[T c.Float] struct {
+	vmax, amax, jmax float64
+
+	p, v, a float64
+}
+
+// create an online trajectory at rest at start
+//
+// vmax and amax limit the absolute velocity and acceleration
+//
+// jmax limits the absolute jerk, if it is not positive the profile is trapezoidal
+func NewOnlineTrajectory[T c.Float](start, vmax, amax, jmax T) *OnlineTrajectory[T] {
+	return &OnlineTrajectory[T]{
+		vmax: math.Abs(float64(vmax)),
+		amax: math.Abs(float64(amax)),
+		jmax: max(0, float64(jmax)),
+		p:    float64(start),
+	}
+}
+
+// reset to rest at pos
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Reset(pos T) {
+	tr.p = float64(pos)
+	tr.v = 0
+	tr.a = 0
+}
+
+// advance the trajectory towards goal by dt and return the new position
+//
+// return the current position if dt is not positive
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Compute(goal, dt T) T {
+	h := float64(dt)
+	if h <= 0 || tr.vmax == 0 || tr.amax == 0 {
+		return T(tr.p)
+	}
+
+	e := float64(goal) - tr.p
+
+	// land exactly once the remaining motion fits in one step of the limits
+	settled := math.Abs(e) <= tr.amax*h*h && math.Abs(tr.v) <= tr.amax*h
+	if settled && (tr.jmax == 0 || math.Abs(tr.a) <= tr.jmax*h) {
+		tr.p = float64(goal)
+		tr.v = 0
+		tr.a = 0
+		return T(tr.p)
+	}
+
+	if tr.jmax == 0 {
+		tr.stepTrapezoidal(e, h)
+	} else {
+		tr.stepJerk(e, h)
+	}
+
+	return T(tr.p)
+}
+
+// follow the fastest velocity that can still stop at the goal with bang bang acceleration
+func (tr *OnlineTrajectory[T]) stepTrapezoidal(e, h float64) {
+	// braking distance tightened by one step of travel
+	reach := max(0, math.Abs(e)-math.Abs(tr.v)*h)
+	vd := math.Copysign(min(tr.vmax, math.Sqrt(2*tr.amax*reach)), e)
+
+	ad := min(tr.amax, math.Abs(vd-tr.v)/h)
+	tr.a = math.Copysign(ad, vd-tr.v)
+	tr.v += tr.a * h
+	tr.p += tr.v*h - tr.a*h*h/2
+}
+
+// cruise towards vmax until the jerk limited stopping distance reaches the goal, then brake
+func (tr *OnlineTrajectory[T]) stepJerk(e, h float64) {
+	// work in the frame where the goal lies in the positive direction
+	s := math.Copysign(1, e)
+	d, v, a := math.Abs(e), s*tr.v, s*tr.a
+	jm := tr.jmax
+
+	var j float64
+	switch {
+	case v > 0 && a < 0 && v+a*h <= a*a/(2*jm):
+		// final ramp, bring velocity and acceleration to zero together
+		j = min(jm, a*a/(2*v))
+	case v+a*math.Abs(a)/(2*jm) > 0 && tr.stopDistance(v, a, tr.brakeLimit(v, a))+v*h >= d:
+		// brake at the shallowest level that still stops at the goal
+		lvl := tr.brakeLevel(d, v, a)
+		j = min(jm, max(-jm, (lvl-a)/h))
+	default:
+		// acceleration that lands on vmax once it is ramped back to zero
+		ev := tr.vmax - v - a*math.Abs(a)/(2*jm)
+		ad := math.Copysign(min(tr.amax, math.Sqrt(2*jm*math.Abs(ev))), ev)
+		j = min(jm, max(-jm, (ad-a)/h))
+	}
+
+	p, v, a := profileSegment{v0: v, a0: a, j: j}.eval(h)
+
+	// absorb the discretisation overshoot of the velocity limit
+	if v > tr.vmax {
+		v = tr.vmax
+		a = min(a, 0)
+	}
+
+	tr.p += s * p
+	tr.v = s * v
+	tr.a = s * a
+}
+
+// return the deepest useful braking acceleration from v and a
+//
+// deeper levels would reverse the motion before the acceleration returns to zero
+func (tr *OnlineTrajectory[T]) brakeLimit(v, a float64) float64 {
+	return max(-tr.amax, -math.Sqrt(a*a/2+tr.jmax*v))
+}
+
+// return the braking acceleration whose stop from v and a covers distance d
+//
+// return the deepest level if even that overshoots
+func (tr *OnlineTrajectory[T]) brakeLevel(d, v, a float64) float64 {
+	lo := tr.brakeLimit(v, a)
+	if tr.stopDistance(v, a, lo) >= d {
+		return lo
+	}
+
+	// the stopping distance grows as the level approaches zero
+	hi := lo * 1e-9
+	for range 60 {
+		mid := (lo + hi) / 2
+		if tr.stopDistance(v, a, mid) < d {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// return the distance travelled by a jerk limited stop from v and a that brakes at level lvl < 0
+func (tr *OnlineTrajectory[T]) stopDistance(v, a, lvl float64) float64 {
+	jm := tr.jmax
+
+	// ramp to the braking level
+	t1 := math.Abs(a-lvl) / jm
+	p, v, a := profileSegment{v0: v, a0: a, j: math.Copysign(jm, lvl-a)}.eval(t1)
+
+	// hold the level until the final ramp exactly cancels the remaining velocity
+	t2 := max(0, (v-a*a/(2*jm))/-a)
+	p, v, a = profileSegment{p0: p, v0: v, a0: a}.eval(t2)
+
+	// ramp back to zero acceleration
+	p, _, _ = profileSegment{p0: p, v0: v, a0: a, j: jm}.eval(-a / jm)
+	return p
+}
+
+// return the current position
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Pos() T {
+	return T(tr.p)
+}
+
+// return the current velocity
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Vel() T {
+	return T(tr.v)
+}
+
+// return the current acceleration
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Acc() T {
+	return T(tr.a)
+}
+
+// report whether the trajectory is at rest at goal
+//
+// time: O(1)
+func (tr *OnlineTrajectory[T]) Done(goal T) bool {
+	return tr.p == float64(goal) && tr.v == 0 && tr.a == 0
+}
@@ -0,0 +1,260 @@
+package control
+
+import (
+	"errors"
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+var (
+	// returned when a transfer function has more zeros than poles
+	ErrImproper = errors.New("control: transfer function is improper")
+
+	// returned when a transfer function denominator is zero
+	ErrZeroDenominator = errors.New("control: transfer function denominator is zero")
+)
+
+// describe a continuous siso lti system as a ratio of polynomials in s
+//
+//	g(s) = (n0*s^m + ... + nm) / (d0*s^k + ... + dk)
+//
+// coefficients are stored in descending powers of s
+//
+// values are immutable, composition returns a new transfer function
+type TransferFunction[T c.Float] struct {
+	num []float64
+	den []float64
+}
+
+// create a transfer function from numerator and denominator coefficients
+//
+// coefficients are given in descending powers of s, leading zeros are ignored
+//
+// return ErrZeroDenominator if den is all zeros
+//
+// return ErrImproper if the numerator degree exceeds the denominator degree
+//
+// time: O(m + k)
+func NewTransferFunction[T c.Float](num, den []T) (TransferFunction[T], error) {
+	n := make([]float64, len(num))
+	for i, v := range num {
+		n[i] = float64(v)
+	}
+	d := make([]float64, len(den))
+	for i, v := range den {
+		d[i] = float64(v)
+	}
+	return newTransferFunction[T](n, d)
+}
+
+func newTransferFunction[T c.Float](num, den []float64) (TransferFunction[T], error) {
+	num = polyTrim(num)
+	den = polyTrim(den)
+
+	if len(den) == 0 {
+		return TransferFunction[T]{}, ErrZeroDenominator
+	}
+	if len(num) == 0 {
+		num = []float64{0}
+	}
+	if len(num) > len(den) {
+		return TransferFunction[T]{}, ErrImproper
+	}
+
+	// normalise so the denominator is monic
+	lead := den[0]
+	for i := range num {
+		num[i] /= lead
+	}
+	for i := range den {
+		den[i] /= lead
+	}
+
+	return TransferFunction[T]{num: num, den: den}, nil
+}
+
+// return a copy of the numerator coefficients in descending powers of s
+//
+// the denominator is normalised to be monic, so coefficients may be scaled
+//
+// time: O(m)
+func (g TransferFunction[T]) Num() []T {
+	return polyTo[T](g.num)
+}
+
+// return a copy of the monic denominator coefficients in descending powers of s
+//
+// time: O(k)
+func (g TransferFunction[T]) Den() []T {
+	return polyTo[T](g.den)
+}
+
+// return the number of poles
+//
+// time: O(1)
+func (g TransferFunction[T]) Order() int {
+	return max(0, len(g.den)-1)
+}
+
+// evaluate g at the complex frequency s
+//
+// time: O(k)
+func (g TransferFunction[T]) Eval(s complex128) complex128 {
+	d := polyEval(g.den, s)
+	if d == 0 {
+		return cmplx.Inf()
+	}
+	return polyEval(g.num, s) / d
+}
+
+// evaluate the frequency response g(jw) at w rad/s
+//
+// time: O(k)
+func (g TransferFunction[T]) Freq(w float64) complex128 {
+	return g.Eval(complex(0, w))
+}
+
+// return the steady state gain g(0)
+//
+// return +inf if g has a pole at the origin
+//
+// time: O(k)
+func (g TransferFunction[T]) DcGain() T {
+	return T(real(g.Eval(0)))
+}
+
+// return the series connection g*h
+//
+// return ErrZeroDenominator if g or h is the zero value without a denominator
+//
+// time: O(k^2)
+func (g TransferFunction[T]) Series(h TransferFunction[T]) (TransferFunction[T], error) {
+	return newTransferFunction[T](polyMul(g.num, h.num), polyMul(g.den, h.den))
+}
+
+// return the parallel connection g+h
+//
+// return ErrZeroDenominator if g or h is the zero value without a denominator
+//
+// time: O(k^2)
+func (g TransferFunction[T]) Parallel(h TransferFunction[T]) (TransferFunction[T], error) {
+	num := polyAdd(polyMul(g.num, h.den), polyMul(h.num, g.den))
+	return newTransferFunction[T](num, polyMul(g.den, h.den))
+}
+
+// return the negative feedback loop g / (1 + g*h)
+//
+// return ErrZeroDenominator or ErrImproper if the loop is ill posed
+//
+// time: O(k^2)
+func (g TransferFunction[T]) Feedback(h TransferFunction[T]) (TransferFunction[T], error) {
+	num := polyMul(g.num, h.den)
+	den := polyAdd(polyMul(g.den, h.den), polyMul(g.num, h.num))
+	return newTransferFunction[T](num, den)
+}
+
+// return the unity negative feedback loop g / (1 + g)
+//
+// return ErrZeroDenominator or ErrImproper if the loop is ill posed
+//
+// time: O(k)
+func (g TransferFunction[T]) UnityFeedback() (TransferFunction[T], error) {
+	return newTransferFunction[T](clonePoly(g.num), polyAdd(g.den, g.num))
+}
+
+// realise the transfer function in controllable canonical form
+//
+// time: O(k^2)
+func (g TransferFunction[T]) StateSpace() *StateSpace[T] {
+	n := g.Order()
+
+	// pad the numerator to the denominator length
+	num := make([]float64, n+1)
+	copy(num[n+1-len(g.num):], g.num)
+
+	a := linalg.New(n, n)
+	b := linalg.New(n, 1)
+	cm := linalg.New(1, n)
+	d := linalg.New(1, 1)
+
+	d0 := num[0]
+	d.Set(0, 0, d0)
+
+	// x1' = x2, ..., xn' = -ak*x1 - ... - a1*xn + u
+	for i := range n - 1 {
+		a.Set(i, i+1, 1)
+	}
+	for j := range n {
+		a.Set(n-1, j, -g.den[n-j])
+		cm.Set(0, j, num[n-j]-g.den[n-j]*d0)
+	}
+	if n > 0 {
+		b.Set(n-1, 0, 1)
+	}
+
+	return newStateSpace[T](a, b, cm, d)
+}
+
+// realise the transfer function as a siso system using the given integrator
+//
+// time: O(k^2)
+func (g TransferFunction[T]) Discretise(method Integrator) *Siso[T] {
+	return &Siso[T]{ss: g.StateSpace().WithIntegrator(method), u: make([]T, 1)}
+}
+
+func polyTrim(p []float64) []float64 {
+	for len(p) > 0 && p[0] == 0 {
+		p = p[1:]
+	}
+	return p
+}
+
+func clonePoly(p []float64) []float64 {
+	out := make([]float64, len(p))
+	copy(out, p)
+	return out
+}
+
+func polyTo[T c.Float](p []float64) []T {
+	out := make([]T, len(p))
+	for i, v := range p {
+		out[i] = T(v)
+	}
+	return out
+}
+
+func polyMul(p, q []float64) []float64 {
+	if len(p) == 0 || len(q) == 0 {
+		return nil
+	}
+	out := make([]float64, len(p)+len(q)-1)
+	for i, a := range p {
+		for j, b := range q {
+			out[i+j] += a * b
+		}
+	}
+	return out
+}
+
+// add two polynomials aligned at the constant term
+func polyAdd(p, q []float64) []float64 {
+	if len(p) < len(q) {
+		p, q = q, p
+	}
+	out := clonePoly(p)
+	off := len(p) - len(q)
+	for i, v := range q {
+		out[off+i] += v
+	}
+	return out
+}
+
+func polyEval(p []float64, s complex128) complex128 {
+	var acc complex128
+	for _, v := range p {
+		acc = acc*s + complex(v, 0)
+	}
+	return acc
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func Read(path string) (map[string][]any, error) {
@@ -88,3 +90,27 @@ func toString(value any) string {
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+func Floats(column []any) ([]float64, error) {
+	out := make([]float64, len(column))
+	for i, value := range column {
+		switch v := value.(type) {
+		case float64:
+			out[i] = v
+		case float32:
+			out[i] = float64(v)
+		case int:
+			out[i] = float64(v)
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+			out[i] = f
+		default:
+			return nil, fmt.Errorf("row %d: unsupported value %v", i+1, value)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,210 @@
+package filter
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// estimate the state of a nonlinear system with an extended kalman filter
+//
+// model
+//
+//	x_k = f(x_{k-1}, u_k, dt) + w,  w ~ N(0, Q)
+//	z_k = h(x_k) + v,               v ~ N(0, R)
+//
+// the models are linearised around the current estimate, with the jacobians given by the
+// user or computed by central differences when they are nil
+//
+// without a process model the state is held constant, as with an identity transition
+//
+// the covariance update uses the joseph form, and every update records the innovation,
+// its covariance and the normalised innovation squared
+//
+// this type is not safe for concurrent use
+type Ekf[T c.Float] struct {
+	estimate[T]
+
+	f    func(x, u []T, dt T) []T
+	fJac func(x, u []T, dt T) [][]T
+	q    linalg.Matrix
+	qFn  func(dt T) [][]T
+
+	h    func(x []T) []T
+	hJac func(x []T) [][]T
+	r    linalg.Matrix
+}
+
+// create an extended kalman filter from the initial estimate x0 and its covariance p0
+//
+// return ErrDimension if p0 is not len(x0) by len(x0)
+//
+// time: O(n^2)
+func NewEkf[T c.Float](x0 []T, p0 [][]T) (*Ekf[T], error) {
+	e, ok := newEstimate(x0, p0)
+	if !ok {
+		return nil, ErrDimension
+	}
+	return &Ekf[T]{estimate: e, q: linalg.New(e.n, e.n)}, nil
+}
+
+// use the process model f with jacobian jac, n by n, and process noise covariance q
+//
+// a nil jac is replaced by central differences
+//
+// return ErrDimension if q is not n by n
+//
+// time: O(n^2)
+func (k *Ekf[T]) Process(f func(x, u []T, dt T) []T, jac func(x, u []T, dt T) [][]T, q [][]T) error {
+	qm, ok := k.noise(q)
+	if !ok {
+		return ErrDimension
+	}
+	k.f, k.fJac, k.q, k.qFn = f, jac, qm, nil
+	return nil
+}
+
+// compute the process noise covariance from the timestep of each prediction
+//
+// it replaces the fixed q given to Process, the shape is checked on every Predict
+//
+// time: O(1)
+func (k *Ekf[T]) ProcessNoiseFunc(q func(dt T) [][]T) {
+	k.qFn = q
+}
+
+// use the measurement model h with jacobian jac, m by n, and noise covariance r, m by m, in Update
+//
+// a nil jac is replaced by central differences
+//
+// return ErrDimension if r is not square
+//
+// time: O(m^2)
+func (k *Ekf[T]) Measurement(h func(x []T) []T, jac func(x []T) [][]T, r [][]T) error {
+	rm, ok := linalg.FromRows(r)
+	if !ok || rm.Rows != rm.Cols {
+		return ErrDimension
+	}
+	k.h, k.hJac, k.r = h, jac, rm
+	return nil
+}
+
+// propagate the estimate over a timestep dt with control input u, which may be nil
+//
+// return ErrDimension if the model outputs do not match the filter, leaving it unchanged
+//
+// time: O(n^3) plus the cost of the model
+func (k *Ekf[T]) Predict(u []T, dt T) error {
+	q := k.q
+	if k.qFn != nil {
+		var ok bool
+		if q, ok = k.noise(k.qFn(dt)); !ok {
+			return ErrDimension
+		}
+	}
+
+	if k.f == nil {
+		k.p = symmetric(k.p.Add(q))
+		return nil
+	}
+
+	x := toFloats(k.f(k.State(), u, dt))
+	if len(x) != k.n {
+		return ErrDimension
+	}
+
+	var f linalg.Matrix
+	if k.fJac != nil {
+		var ok bool
+		f, ok = linalg.FromRows(k.fJac(k.State(), u, dt))
+		if !ok || f.Rows != k.n || f.Cols != k.n {
+			return ErrDimension
+		}
+	} else {
+		var ok bool
+		f, ok = numericJacobian(func(v []float64) []float64 {
+			return toFloats(k.f(fromFloats[T](v, k.n), u, dt))
+		}, k.x, k.n)
+		if !ok {
+			return ErrDimension
+		}
+	}
+
+	k.x = x
+	k.p = symmetric(f.Mul(k.p).Mul(f.T()).Add(q))
+	return nil
+}
+
+// correct the estimate with measurement z using the model set by Measurement
+//
+// return ErrDimension if no measurement model is set or the shapes do not match,
+// and ErrSingular if the innovation covariance cannot be inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3) plus the cost of the model
+func (k *Ekf[T]) Update(z []T) error {
+	if k.h == nil {
+		return ErrDimension
+	}
+	return k.update(z, k.h, k.hJac, k.r)
+}
+
+// correct the estimate with measurement z from a sensor with its own model h, jacobian jac and noise r
+//
+// a nil jac is replaced by central differences
+//
+// return ErrDimension if the shapes do not match and ErrSingular if the innovation covariance
+// cannot be inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3) plus the cost of the model
+func (k *Ekf[T]) UpdateWith(z []T, h func(x []T) []T, jac func(x []T) [][]T, r [][]T) error {
+	rm, ok := measurementNoise(r, len(z))
+	if !ok || h == nil {
+		return ErrDimension
+	}
+	return k.update(z, h, jac, rm)
+}
+
+func (k *Ekf[T]) update(z []T, h func(x []T) []T, jac func(x []T) [][]T, r linalg.Matrix) error {
+	m := len(z)
+	hx := toFloats(h(k.State()))
+	if len(hx) != m || r.Rows != m {
+		return ErrDimension
+	}
+
+	var hm linalg.Matrix
+	if jac != nil {
+		var ok bool
+		hm, ok = linalg.FromRows(jac(k.State()))
+		if !ok || hm.Rows != m || hm.Cols != k.n {
+			return ErrDimension
+		}
+	} else {
+		var ok bool
+		hm, ok = numericJacobian(func(v []float64) []float64 {
+			return toFloats(h(fromFloats[T](v, k.n)))
+		}, k.x, m)
+		if !ok {
+			return ErrDimension
+		}
+	}
+
+	y := make([]float64, m)
+	for i := range y {
+		y[i] = float64(z[i]) - hx[i]
+	}
+
+	// S = H*P*H^t + R
+	pht := k.p.Mul(hm.T())
+	s := symmetric(hm.Mul(pht).Add(r))
+
+	return k.correct(y, s, pht, func(gain linalg.Matrix) linalg.Matrix {
+		// joseph form
+		ikh := linalg.Identity(k.n).Sub(gain.Mul(hm))
+		return ikh.Mul(k.p).Mul(ikh.T()).Add(gain.Mul(r).Mul(gain.T()))
+	})
+}
@@ -0,0 +1,245 @@
+package filter
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// estimate a state from noisy measurements with separate predict and update steps
+//
+// Kalman, Ekf and Ukf satisfy this interface, so they can be swapped behind the same loop
+type Estimator[T c.Float] interface {
+	// propagate the estimate over a timestep dt with control input u, which may be nil
+	Predict(u []T, dt T) error
+
+	// correct the estimate with measurement z using the default measurement model
+	Update(z []T) error
+
+	// reset the estimate and covariance
+	Reset(x0 []T, p0 [][]T) error
+
+	// return a copy of the state estimate
+	State() []T
+
+	// return a copy of the estimate covariance
+	Covariance() [][]T
+
+	// return a copy of the innovation of the last update
+	Innovation() []T
+
+	// return a copy of the innovation covariance of the last update
+	InnovationCov() [][]T
+
+	// return the normalised innovation squared of the last update
+	Nis() T
+
+	// reject updates whose normalised innovation squared exceeds threshold with ErrOutlier
+	Gate(threshold T)
+}
+
+// hold the gaussian estimate and the last innovation shared by the kalman filters
+type estimate[T c.Float] struct {
+	n int
+
+	x []float64
+	p linalg.Matrix
+
+	// last update
+	y   []float64
+	s   linalg.Matrix
+	nis float64
+
+	// nis threshold above which updates are rejected, disabled when not positive
+	gate float64
+}
+
+// create an estimate from x0 and its covariance p0, false if the shapes do not match
+func newEstimate[T c.Float](x0 []T, p0 [][]T) (estimate[T], bool) {
+	n := len(x0)
+	p, ok := linalg.FromRows(p0)
+	if n == 0 || !ok || p.Rows != n || p.Cols != n {
+		return estimate[T]{}, false
+	}
+	return estimate[T]{n: n, x: toFloats(x0), p: p}, true
+}
+
+// reset the estimate and covariance and clear the last innovation
+//
+// return ErrDimension if the shapes do not match the filter, leaving it unchanged
+//
+// time: O(n^2)
+func (e *estimate[T]) Reset(x0 []T, p0 [][]T) error {
+	p, ok := linalg.FromRows(p0)
+	if len(x0) != e.n || !ok || p.Rows != e.n || p.Cols != e.n {
+		return ErrDimension
+	}
+
+	e.x, e.p = toFloats(x0), p
+	e.y, e.s, e.nis = nil, linalg.Matrix{}, 0
+	return nil
+}
+
+// return a copy of the state estimate
+//
+// time: O(n)
+func (e *estimate[T]) State() []T {
+	return fromFloats[T](e.x, e.n)
+}
+
+// return a copy of the estimate covariance
+//
+// time: O(n^2)
+func (e *estimate[T]) Covariance() [][]T {
+	return linalg.ToRows[T](e.p)
+}
+
+// return a copy of the innovation z - h(x) of the last update, nil before the first update
+//
+// time: O(m)
+func (e *estimate[T]) Innovation() []T {
+	if e.y == nil {
+		return nil
+	}
+	return fromFloats[T](e.y, len(e.y))
+}
+
+// return a copy of the innovation covariance of the last update, nil before the first update
+//
+// time: O(m^2)
+func (e *estimate[T]) InnovationCov() [][]T {
+	if e.s.Rows == 0 {
+		return nil
+	}
+	return linalg.ToRows[T](e.s)
+}
+
+// return the normalised innovation squared y^t*S^-1*y of the last update
+//
+// for a consistent filter it follows a chi square distribution with m degrees of freedom
+//
+// time: O(1)
+func (e *estimate[T]) Nis() T {
+	return T(e.nis)
+}
+
+// reject measurements whose normalised innovation squared exceeds threshold
+//
+// a consistent filter has a nis chi square distributed with m degrees of freedom, so the
+// threshold is its quantile for the accepted false rejection rate, 6.63 for m = 1, 9.21 for
+// m = 2 and 11.34 for m = 3 at 1%
+//
+// a rejected update returns ErrOutlier and leaves the estimate unchanged, while Innovation,
+// InnovationCov and Nis report the rejected measurement
+//
+// the covariance keeps growing while measurements are rejected, so a persistent change is
+// eventually accepted
+//
+// a threshold that is not positive disables the gate
+//
+// time: O(1)
+func (e *estimate[T]) Gate(threshold T) {
+	e.gate = float64(threshold)
+}
+
+// apply a correction given the innovation y, its covariance s and the cross covariance pxz
+//
+// the gain is K = pxz*S^-1, and the covariance becomes covariance(K)
+//
+// return ErrSingular if s cannot be inverted or the result is not finite and ErrOutlier if the
+// nis exceeds the gate, leaving the estimate unchanged
+func (e *estimate[T]) correct(y []float64, s, pxz linalg.Matrix, covariance func(gain linalg.Matrix) linalg.Matrix) error {
+	m := len(y)
+
+	// K = pxz*S^-1, solved as S*K^t = pxz^t
+	kt, ok := s.Solve(pxz.T())
+	if !ok {
+		return ErrSingular
+	}
+	gain := kt.T()
+
+	sy, _ := s.Solve(linalg.Matrix{Rows: m, Cols: 1, Data: y})
+	nis := 0.0
+	for i := range y {
+		nis += y[i] * sy.Data[i]
+	}
+	if e.gate > 0 && nis > e.gate {
+		e.y, e.s, e.nis = y, s, nis
+		return ErrOutlier
+	}
+
+	ky := make([]float64, e.n)
+	gain.MulVec(y, ky)
+	x := make([]float64, e.n)
+	for i := range x {
+		x[i] = e.x[i] + ky[i]
+	}
+	if !linalg.Finite(x) {
+		return ErrSingular
+	}
+
+	e.x, e.p = x, symmetric(covariance(gain))
+	e.y, e.s, e.nis = y, s, nis
+	return nil
+}
+
+// convert f and q to n by n matrices
+func (e *estimate[T]) square(f, q [][]T) (fm, qm linalg.Matrix, ok bool) {
+	fm, okf := linalg.FromRows(f)
+	qm, okq := linalg.FromRows(q)
+	if !okf || !okq || fm.Rows != e.n || fm.Cols != e.n || qm.Rows != e.n || qm.Cols != e.n {
+		return fm, qm, false
+	}
+	return fm, qm, true
+}
+
+// convert an n by n noise covariance q to a matrix
+func (e *estimate[T]) noise(q [][]T) (linalg.Matrix, bool) {
+	qm, ok := linalg.FromRows(q)
+	return qm, ok && qm.Rows == e.n && qm.Cols == e.n
+}
+
+// convert a m by m noise covariance r to a matrix
+func measurementNoise[T c.Float](r [][]T, m int) (linalg.Matrix, bool) {
+	rm, ok := linalg.FromRows(r)
+	return rm, ok && rm.Rows == m && rm.Cols == m
+}
+
+// return the jacobian of fn at x by central differences
+//
+// return false if fn does not return m values
+func numericJacobian(fn func(x []float64) []float64, x []float64, m int) (linalg.Matrix, bool) {
+	n := len(x)
+	j := linalg.New(m, n)
+	xp := make([]float64, n)
+	for col := range n {
+		h := 1e-6 * max(1, math.Abs(x[col]))
+
+		copy(xp, x)
+		xp[col] = x[col] + h
+		fp := fn(xp)
+		xp[col] = x[col] - h
+		fm := fn(xp)
+		if len(fp) != m || len(fm) != m {
+			return linalg.Matrix{}, false
+		}
+
+		for row := range m {
+			j.Data[row*n+col] = (fp[row] - fm[row]) / (2 * h)
+		}
+	}
+	return j, true
+}
+
+// return (m + m^t)/2
+func symmetric(m linalg.Matrix) linalg.Matrix {
+	out := m.Clone()
+	for i := range m.Rows {
+		for j := i + 1; j < m.Cols; j++ {
+			v := (m.Data[i*m.Cols+j] + m.Data[j*m.Cols+i]) / 2
+			out.Data[i*m.Cols+j], out.Data[j*m.Cols+i] = v, v
+		}
+	}
+	return out
+}
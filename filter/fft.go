@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// compute the discrete fourier transform of x in place
+//
+// len(x) must be a power of two, inverse computes the inverse transform including the 1/n scale
+//
+// time: O(n log n)
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// bit reversal permutation
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range n {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+
+	// twiddle factors of the largest stage, smaller stages take every stride-th one
+	tw := make([]complex128, n/2)
+	for k := range tw {
+		tw[k] = cmplx.Exp(complex(0, sign*2*math.Pi*float64(k)/float64(n)))
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half, stride := size/2, n/size
+		for start := 0; start < n; start += size {
+			for k := range half {
+				a, b := x[start+k], tw[k*stride]*x[start+k+half]
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
+
+// return the smallest power of two not below n
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
@@ -0,0 +1,922 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/cmplx"
+	"slices"
+	"testing"
+
+	"github.com/vistormu/go-dsa/geometry"
+)
+
+func TestSnapshot(t *testing.T) {
+	lp := NewLowPass(0.3)
+	ks := NewKalmanScalar(0.01, 0.5, 1, 0.0).WithGate(6.63)
+	kv := NewKalmanConstVel(0.1, 0.2, 0.0, 0, 1, 1)
+	mean := NewMean[float64](4)
+	median := NewMedian[int](5)
+
+	input := func(i int) float64 { return float64(i%7) - 0.5*float64(i%3) }
+	for i := range 20 {
+		x := input(i)
+		lp.Compute(x)
+		ks.Compute(x)
+		kv.Compute(x, 0.1)
+		mean.Compute(x)
+		median.Compute(i % 7)
+	}
+
+	// the last measurement is rejected by the gate
+	ks.Compute(100)
+
+	var lp2 LowPass[float64]
+	var ks2 KalmanScalar[float64]
+	var kv2 KalmanConstVel[float64]
+	var mean2 Mean[float64]
+	var median2 Median[int]
+
+	bin := func(m interface{ MarshalBinary() ([]byte, error) }, u interface{ UnmarshalBinary([]byte) error }) {
+		data, _ := m.MarshalBinary()
+		if err := u.UnmarshalBinary(data); err != nil {
+			t.Fatalf("binary restore: %v", err)
+		}
+	}
+	js := func(m any, u any) {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("json snapshot: %v", err)
+		}
+		if err := json.Unmarshal(data, u); err != nil {
+			t.Fatalf("json restore: %v", err)
+		}
+	}
+	bin(lp, &lp2)
+	js(ks, &ks2)
+	if !ks2.Rejected() || ks2.Nis() != ks.Nis() {
+		t.Fatalf("kalman scalar gate state: rejected %v, nis %v, want %v", ks2.Rejected(), ks2.Nis(), ks.Nis())
+	}
+	var ks3 KalmanScalar[float64]
+	bin(ks, &ks3)
+	if ks3 != *ks {
+		t.Fatalf("kalman scalar binary restore %+v, want %+v", ks3, *ks)
+	}
+
+	bin(kv, &kv2)
+	js(mean, &mean2)
+	bin(median, &median2)
+
+	for i := 20; i < 40; i++ {
+		x := input(i)
+		if a, b := lp.Compute(x), lp2.Compute(x); a != b {
+			t.Fatalf("low pass %d: %v != %v", i, a, b)
+		}
+		if a, b := ks.Compute(x), ks2.Compute(x); a != b {
+			t.Fatalf("kalman scalar %d: %v != %v", i, a, b)
+		}
+		if a, b := kv.Compute(x, 0.1), kv2.Compute(x, 0.1); a != b || kv.Vel() != kv2.Vel() {
+			t.Fatalf("kalman const vel %d: %v != %v", i, a, b)
+		}
+		if a, b := mean.Compute(x), mean2.Compute(x); a != b {
+			t.Fatalf("mean %d: %v != %v", i, a, b)
+		}
+		if a, b := median.Compute(i%7), median2.Compute(i%7); a != b {
+			t.Fatalf("median %d: %v != %v", i, a, b)
+		}
+	}
+
+	data, _ := mean.MarshalBinary()
+	if err := median2.UnmarshalBinary(data); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for wrong kind, got %v", err)
+	}
+	if err := mean2.UnmarshalJSON([]byte(`{"window": 1, "values": [1, 2]}`)); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for overfull window, got %v", err)
+	}
+	if err := lp2.UnmarshalJSON([]byte(`{`)); !errors.Is(err, ErrSnapshot) {
+		t.Fatalf("expected ErrSnapshot for malformed json, got %v", err)
+	}
+}
+
+func TestKalman(t *testing.T) {
+	q, r := 0.5, 0.2
+	ref := NewKalmanConstVel(q, r, 0.0, 0, 1, 1)
+
+	k, err := NewKalman([]float64{0, 0}, [][]float64{{1, 0}, {0, 1}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	k.TransitionFunc(func(dt float64) (f, qd [][]float64) {
+		dt2 := dt * dt
+		return [][]float64{{1, dt}, {0, 1}},
+			[][]float64{{q * dt2 * dt2 / 4, q * dt2 * dt / 2}, {q * dt2 * dt / 2, q * dt2}}
+	})
+	if err := k.Measurement([][]float64{{1, 0}}, [][]float64{{r}}); err != nil {
+		t.Fatalf("measurement: %v", err)
+	}
+
+	// uneven timesteps match the hand unrolled filter
+	for i := range 50 {
+		dt := 0.05 + 0.01*float64(i%4)
+		z := 2*float64(i)*0.06 + 0.1*math.Sin(float64(i))
+		want := ref.Compute(z, dt)
+
+		if err := k.Predict(nil, dt); err != nil {
+			t.Fatalf("predict: %v", err)
+		}
+		if err := k.Update([]float64{z}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		x := k.State()
+		if math.Abs(x[0]-want) > 1e-9 || math.Abs(x[1]-ref.Vel()) > 1e-9 {
+			t.Fatalf("step %d: %v, want %v %v", i, x, want, ref.Vel())
+		}
+	}
+
+	y, s := k.Innovation(), k.InnovationCov()
+	if nis := y[0] * y[0] / s[0][0]; math.Abs(k.Nis()-nis) > 1e-12 {
+		t.Fatalf("nis %v, want %v", k.Nis(), nis)
+	}
+	p := k.Covariance()
+	if p[0][1] != p[1][0] || p[0][0] <= 0 || p[1][1] <= 0 {
+		t.Fatalf("covariance %v", p)
+	}
+
+	// a second sensor measuring velocity directly
+	if err := k.UpdateWith([]float64{2}, [][]float64{{0, 1}}, [][]float64{{0.01}}); err != nil {
+		t.Fatalf("update with: %v", err)
+	}
+	if v := k.State()[1]; math.Abs(v-2) > 0.1 {
+		t.Fatalf("velocity after fusion %v", v)
+	}
+
+	// control input drives a known acceleration
+	c, _ := NewKalman([]float64{0, 0}, [][]float64{{0, 0}, {0, 0}})
+	dt := 0.1
+	c.Transition([][]float64{{1, dt}, {0, 1}}, [][]float64{{0, 0}, {0, 0}})
+	c.ControlInput([][]float64{{dt * dt / 2}, {dt}})
+	for range 10 {
+		c.Predict([]float64{1}, dt)
+	}
+	if x := c.State(); math.Abs(x[0]-0.5) > 1e-12 || math.Abs(x[1]-1) > 1e-12 {
+		t.Fatalf("control input state %v", x)
+	}
+
+	if _, err := NewKalman([]float64{0, 0}, [][]float64{{1}}); !errors.Is(err, ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := k.Update([]float64{1, 2}); !errors.Is(err, ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := c.Predict([]float64{1, 2}, dt); !errors.Is(err, ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := c.UpdateWith([]float64{1}, [][]float64{{1, 0}}, [][]float64{{0}}); !errors.Is(err, ErrSingular) {
+		t.Fatalf("expected ErrSingular, got %v", err)
+	}
+}
+
+func TestNonlinearKalman(t *testing.T) {
+	var _ Estimator[float64] = (*Kalman[float64])(nil)
+	var _ Estimator[float64] = (*Ekf[float64])(nil)
+	var _ Estimator[float64] = (*Ukf[float64])(nil)
+
+	// on a linear model all three filters agree
+	x0 := []float64{0, 0}
+	p0 := [][]float64{{1, 0}, {0, 1}}
+	transition := func(x, u []float64, dt float64) []float64 { return []float64{x[0] + dt*x[1], x[1]} }
+	noise := func(dt float64) [][]float64 { return [][]float64{{0.01 * dt, 0}, {0, 0.1 * dt}} }
+	position := func(x []float64) []float64 { return x[:1] }
+	r := [][]float64{{0.2}}
+
+	lin, _ := NewKalman(x0, p0)
+	lin.TransitionFunc(func(dt float64) (f, q [][]float64) { return [][]float64{{1, dt}, {0, 1}}, noise(dt) })
+	lin.Measurement([][]float64{{1, 0}}, r)
+
+	ekf, _ := NewEkf(x0, p0)
+	ekf.Process(transition, nil, [][]float64{{0, 0}, {0, 0}})
+	ekf.ProcessNoiseFunc(noise)
+	ekf.Measurement(position, nil, r)
+
+	ukf, _ := NewUkf(x0, p0)
+	ukf.WithSigma(0.5, 2, 1)
+	ukf.Process(transition, [][]float64{{0, 0}, {0, 0}})
+	ukf.ProcessNoiseFunc(noise)
+	ukf.Measurement(position, r)
+
+	for i := range 30 {
+		dt := 0.1 + 0.02*float64(i%3)
+		z := []float64{0.3*float64(i) + 0.05*math.Sin(3*float64(i))}
+		for _, f := range []Estimator[float64]{lin, ekf, ukf} {
+			if err := f.Predict(nil, dt); err != nil {
+				t.Fatalf("predict: %v", err)
+			}
+			if err := f.Update(z); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+		}
+
+		want := lin.State()
+		for name, f := range map[string]Estimator[float64]{"ekf": ekf, "ukf": ukf} {
+			got := f.State()
+			if math.Abs(got[0]-want[0]) > 1e-6 || math.Abs(got[1]-want[1]) > 1e-6 {
+				t.Fatalf("%s step %d: %v, want %v", name, i, got, want)
+			}
+			if math.Abs(f.Nis()-lin.Nis()) > 1e-6 {
+				t.Fatalf("%s nis %v, want %v", name, f.Nis(), lin.Nis())
+			}
+		}
+	}
+
+	// unicycle localisation from range and bearing to a landmark at the origin and range
+	// to a second one, state [x, y, heading], control [speed, turn rate], the bearing is
+	// measured as a unit vector so it never wraps
+	unicycle := func(x, u []float64, dt float64) []float64 {
+		return []float64{x[0] + dt*u[0]*math.Cos(x[2]), x[1] + dt*u[0]*math.Sin(x[2]), x[2] + dt*u[1]}
+	}
+	unicycleJac := func(x, u []float64, dt float64) [][]float64 {
+		return [][]float64{
+			{1, 0, -dt * u[0] * math.Sin(x[2])},
+			{0, 1, dt * u[0] * math.Cos(x[2])},
+			{0, 0, 1},
+		}
+	}
+	rangeBearing := func(x []float64) []float64 {
+		b := math.Atan2(-x[1], -x[0]) - x[2]
+		return []float64{math.Hypot(x[0], x[1]), math.Cos(b), math.Sin(b), math.Hypot(x[0]-10, x[1])}
+	}
+	gps := func(x []float64) []float64 { return x[:2] }
+
+	truth := []float64{5, 0, math.Pi / 2}
+	guess := []float64{4.5, 0.5, math.Pi/2 + 0.2}
+	p0 = [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 0.1}}
+	q := [][]float64{{1e-4, 0, 0}, {0, 1e-4, 0}, {0, 0, 1e-4}}
+	rz := [][]float64{{0.01, 0, 0, 0}, {0, 0.001, 0, 0}, {0, 0, 0.001, 0}, {0, 0, 0, 0.01}}
+
+	ekfA, _ := NewEkf(guess, p0)
+	ekfA.Process(unicycle, unicycleJac, q)
+	ekfA.Measurement(rangeBearing, nil, rz)
+	ekfN, _ := NewEkf(guess, p0)
+	ekfN.Process(unicycle, nil, q)
+	ekfN.Measurement(rangeBearing, nil, rz)
+	ukf, _ = NewUkf(guess, p0)
+	ukf.Process(unicycle, q)
+	ukf.Measurement(rangeBearing, rz)
+
+	dt := 0.05
+	u := []float64{1, 0.2}
+	for i := range 400 {
+		truth = unicycle(truth, u, dt)
+		z := rangeBearing(truth)
+		z[0] += 0.02 * math.Sin(7*float64(i))
+		z[1] += 0.01 * math.Cos(5*float64(i))
+		z[2] += 0.01 * math.Sin(3*float64(i))
+		z[3] += 0.02 * math.Cos(11*float64(i))
+
+		for _, f := range []Estimator[float64]{ekfA, ekfN, ukf} {
+			f.Predict(u, dt)
+			if err := f.Update(z); err != nil {
+				t.Fatalf("step %d update: %v", i, err)
+			}
+		}
+
+		a, n := ekfA.State(), ekfN.State()
+		for j := range a {
+			if math.Abs(a[j]-n[j]) > 1e-4 {
+				t.Fatalf("numeric jacobian step %d: %v, analytic %v", i, n, a)
+			}
+		}
+	}
+
+	for name, f := range map[string]Estimator[float64]{"ekf": ekfA, "ukf": ukf} {
+		x := f.State()
+		if math.Hypot(x[0]-truth[0], x[1]-truth[1]) > 0.1 || math.Abs(x[2]-truth[2]) > 0.05 {
+			t.Fatalf("%s estimate %v, truth %v", name, x, truth)
+		}
+	}
+
+	// a second sensor with its own model
+	if err := ukf.UpdateWith(truth[:2], gps, [][]float64{{0.01, 0}, {0, 0.01}}); err != nil {
+		t.Fatalf("ukf update with: %v", err)
+	}
+	if err := ekfA.UpdateWith(truth[:2], gps, nil, [][]float64{{0.01, 0}, {0, 0.01}}); err != nil {
+		t.Fatalf("ekf update with: %v", err)
+	}
+	if err := ekfA.UpdateWith(truth[:2], gps, nil, [][]float64{{0.01}}); !errors.Is(err, ErrDimension) {
+		t.Fatalf("expected ErrDimension, got %v", err)
+	}
+	if err := ukf.Reset([]float64{0, 0, 0}, [][]float64{{-1, 0, 0}, {0, 1, 0}, {0, 0, 1}}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := ukf.Predict(u, dt); !errors.Is(err, ErrSingular) {
+		t.Fatalf("expected ErrSingular for indefinite covariance, got %v", err)
+	}
+}
+
+func TestKalmanKinematic(t *testing.T) {
+	// constant acceleration recovers the acceleration of a parabola
+	acc, err := NewKalmanConstAcc(1e-4, 0.01, 0.0, 0, 0, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt := 0.02
+	for i := 1; i <= 500; i++ {
+		tt := float64(i) * dt
+		acc.Compute(1.5*tt*tt+0.05*math.Sin(13*tt), dt)
+	}
+	if math.Abs(acc.Acc()-3) > 0.2 || math.Abs(acc.Vel()-30) > 0.5 {
+		t.Fatalf("acc %v vel %v", acc.Acc(), acc.Vel())
+	}
+	if p := acc.Covariance(); len(p) != 3 || p[0][2] != p[2][0] || p[2][2] <= 0 {
+		t.Fatalf("covariance %v", p)
+	}
+
+	// a 2d constant velocity filter matches two independent single axis filters
+	q, r := 0.3, 0.1
+	kin, err := NewKalmanKinematic(2, ConstantVelocity, q, r, geometry.NewVector(0.0, 0), 1, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fx := NewKalmanConstVel(q, r, 0.0, 0, 1, 1)
+	fy := NewKalmanConstVel(q, r, 0.0, 0, 1, 1)
+	for i := range 50 {
+		dt := 0.05 + 0.01*float64(i%3)
+		z := geometry.NewVector(float64(i)*0.1, math.Cos(float64(i)))
+		pos := kin.Compute(z, dt)
+		if x, y := fx.Compute(z.X, dt), fy.Compute(z.Y, dt); math.Abs(pos.X-x) > 1e-9 || math.Abs(pos.Y-y) > 1e-9 {
+			t.Fatalf("step %d: %v, want %v %v", i, pos, x, y)
+		}
+		if v := kin.Vel(); math.Abs(v.X-fx.Vel()) > 1e-9 || math.Abs(v.Y-fy.Vel()) > 1e-9 {
+			t.Fatalf("step %d: vel %v, want %v %v", i, v, fx.Vel(), fy.Vel())
+		}
+	}
+	if a := kin.Acc(); a != (geometry.Vector[float64]{}) {
+		t.Fatalf("constant velocity acc %v", a)
+	}
+	p := kin.Covariance()
+	want := fx.Covariance()
+	if len(p) != 4 || math.Abs(p[0][1]-want[0][1]) > 1e-9 || p[0][2] != 0 || p[1][3] != 0 {
+		t.Fatalf("covariance %v, x block %v", p, want)
+	}
+
+	// fusing velocity measurements matches the general filter axis by axis
+	kin, _ = NewKalmanKinematic(2, ConstantVelocity, q, r, geometry.NewVector(0.0, 0), 1, 1, 0)
+	fx = NewKalmanConstVel(q, r, 0.0, 0, 1, 1)
+	if err := kin.VelocityNoise(0.05); err != nil {
+		t.Fatal(err)
+	}
+	if err := fx.VelocityNoise(0.05); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 50 {
+		dt := 0.05 + 0.01*float64(i%3)
+		z, v := float64(i)*0.1+0.2*math.Sin(float64(i)), 2+math.Cos(float64(i))
+		pos := kin.ComputeVel(geometry.NewVector(z, 0), geometry.NewVector(v, 0), dt)
+		if x := fx.ComputeVel(z, v, dt); math.Abs(pos.X-x) > 1e-9 || math.Abs(kin.Vel().X-fx.Vel()) > 1e-9 {
+			t.Fatalf("step %d: %v %v, want %v %v", i, pos.X, kin.Vel().X, x, fx.Vel())
+		}
+	}
+	if p, want := kin.Covariance(), fx.Covariance(); math.Abs(p[0][1]-want[0][1]) > 1e-9 || math.Abs(p[1][1]-want[1][1]) > 1e-9 {
+		t.Fatalf("covariance %v, x block %v", p, want)
+	}
+	if err := fx.VelocityNoise(-1); !errors.Is(err, ErrNoise) {
+		t.Fatalf("expected ErrNoise for negative rv, got %v", err)
+	}
+
+	// fusing velocity measurements tightens the velocity estimate
+	vel := geometry.NewVector(1.0, -2, 0.5)
+	plain, _ := NewKalmanKinematic(3, ConstantAcceleration, 0.01, 0.5, geometry.Vector[float64]{}, 1, 10, 1)
+	fused, _ := NewKalmanKinematic(3, ConstantAcceleration, 0.01, 0.5, geometry.Vector[float64]{}, 1, 10, 1)
+	if err := fused.VelocityNoise(0.001); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 20; i++ {
+		tt := float64(i) * 0.05
+		noise := 0.3 * math.Sin(7*tt)
+		z := vel.Scale(tt).Add(geometry.NewVector(noise, -noise, noise))
+		plain.Compute(z, 0.05)
+		fused.ComputeVel(z, vel, 0.05)
+	}
+	if d := fused.Vel().Sub(vel).Len(); d > 0.1 || d >= plain.Vel().Sub(vel).Len() {
+		t.Fatalf("fused vel %v, plain %v", fused.Vel(), plain.Vel())
+	}
+	if fp, pp := fused.Covariance(), plain.Covariance(); len(fp) != 9 || fp[1][1] >= pp[1][1] {
+		t.Fatalf("fused velocity variance %v, plain %v", fp[1][1], pp[1][1])
+	}
+
+	fused.Reset(geometry.NewVector(1.0, 2, 3), 1, 1, 1)
+	if fused.Pos() != geometry.NewVector(1.0, 2, 3) || fused.Vel() != (geometry.Vector[float64]{}) {
+		t.Fatalf("reset %v %v", fused.Pos(), fused.Vel())
+	}
+
+	// noise variances that would make the update singular are rejected
+	if _, err := NewKalmanKinematic(2, ConstantVelocity, 0.1, 0, geometry.Vector[float64]{}, 1, 1, 0); !errors.Is(err, ErrNoise) {
+		t.Fatalf("expected ErrNoise for r = 0, got %v", err)
+	}
+	if _, err := NewKalmanKinematic(4, ConstantVelocity, 0.1, 0.1, geometry.Vector[float64]{}, 1, 1, 0); !errors.Is(err, ErrDimension) {
+		t.Fatalf("expected ErrDimension for 4 axes, got %v", err)
+	}
+	if _, err := NewKalmanConstAcc(-1, 0.1, 0.0, 0, 0, 1, 1, 1); !errors.Is(err, ErrNoise) {
+		t.Fatalf("expected ErrNoise for negative q, got %v", err)
+	}
+	if err := fused.VelocityNoise(0); !errors.Is(err, ErrNoise) {
+		t.Fatalf("expected ErrNoise for rv = 0, got %v", err)
+	}
+	if err := acc.VelocityNoise(math.NaN()); !errors.Is(err, ErrNoise) {
+		t.Fatalf("expected ErrNoise for nan rv, got %v", err)
+	}
+}
+
+func TestIir(t *testing.T) {
+	fs := 1000.0
+	warp := func(f float64) float64 { return math.Tan(math.Pi * f / fs) }
+	cheb := func(n int, x float64) float64 {
+		x = math.Abs(x)
+		if x <= 1 {
+			return math.Cos(float64(n) * math.Acos(x))
+		}
+		return math.Cosh(float64(n) * math.Acosh(x))
+	}
+
+	// analog prototype frequency for each band after prewarping
+	lowPass := func(f, fc float64) float64 { return warp(f) / warp(fc) }
+	highPass := func(f, fc float64) float64 { return warp(fc) / warp(f) }
+	bandPass := func(f, f1, f2 float64) float64 {
+		w, w1, w2 := warp(f), warp(f1), warp(f2)
+		return (w*w - w1*w2) / (w * (w2 - w1))
+	}
+	bandStop := func(f, f1, f2 float64) float64 {
+		w, w1, w2 := warp(f), warp(f1), warp(f2)
+		return w * (w2 - w1) / (w1*w2 - w*w)
+	}
+
+	ripple := 1.0
+	eps2 := math.Pow(10, ripple/10) - 1
+
+	cases := []struct {
+		name   string
+		design func() (*Iir[float64], error)
+		power  func(f float64) float64
+	}{
+		{"butter lp 5", func() (*Iir[float64], error) { return Butterworth(5, LowPassBand, fs, 100.0) },
+			func(f float64) float64 { return 1 / (1 + math.Pow(lowPass(f, 100), 10)) }},
+		{"butter hp 4", func() (*Iir[float64], error) { return Butterworth(4, HighPassBand, fs, 50.0) },
+			func(f float64) float64 { return 1 / (1 + math.Pow(highPass(f, 50), 8)) }},
+		{"butter bp 3", func() (*Iir[float64], error) { return Butterworth(3, BandPassBand, fs, 100.0, 200) },
+			func(f float64) float64 { return 1 / (1 + math.Pow(bandPass(f, 100, 200), 6)) }},
+		{"butter bs 2", func() (*Iir[float64], error) { return Butterworth(2, BandStopBand, fs, 40.0, 60) },
+			func(f float64) float64 { return 1 / (1 + math.Pow(bandStop(f, 40, 60), 4)) }},
+		{"cheby lp 4", func() (*Iir[float64], error) { return Chebyshev1(4, ripple, LowPassBand, fs, 120.0) },
+			func(f float64) float64 { return 1 / (1 + eps2*math.Pow(cheb(4, lowPass(f, 120)), 2)) }},
+		{"cheby hp 5", func() (*Iir[float64], error) { return Chebyshev1(5, ripple, HighPassBand, fs, 200.0) },
+			func(f float64) float64 { return 1 / (1 + eps2*math.Pow(cheb(5, highPass(f, 200)), 2)) }},
+		{"cheby bp 4", func() (*Iir[float64], error) { return Chebyshev1(4, ripple, BandPassBand, fs, 150.0, 300) },
+			func(f float64) float64 { return 1 / (1 + eps2*math.Pow(cheb(4, bandPass(f, 150, 300)), 2)) }},
+		{"cheby bs 3", func() (*Iir[float64], error) { return Chebyshev1(3, ripple, BandStopBand, fs, 80.0, 120) },
+			func(f float64) float64 { return 1 / (1 + eps2*math.Pow(cheb(3, bandStop(f, 80, 120)), 2)) }},
+	}
+
+	for _, tc := range cases {
+		f, err := tc.design()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for freq := 5.0; freq < fs/2; freq += 7 {
+			got := math.Pow(cmplx.Abs(f.Response(freq, fs)), 2)
+			if want := tc.power(freq); math.Abs(got-want) > 1e-9 {
+				t.Fatalf("%s at %v hz: |h|^2 %v, want %v", tc.name, freq, got, want)
+			}
+		}
+	}
+
+	// -3 db at the butterworth cutoff
+	lp, _ := Butterworth(8, LowPassBand, fs, 10.0)
+	if g := lp.Gain(10, fs); math.Abs(g+3.0103) > 1e-3 {
+		t.Fatalf("cutoff gain %v db", g)
+	}
+
+	// a high order, low cutoff design stays stable in float32 and passes its pass band
+	lp32, _ := Butterworth[float32](8, LowPassBand, float32(fs), 10)
+	if len(lp32.Sections()) != 4 {
+		t.Fatalf("sections %d", len(lp32.Sections()))
+	}
+	var peak, stop float64
+	for i := range 4000 {
+		tt := float64(i) / fs
+		y := float64(lp32.Compute(float32(math.Sin(2 * math.Pi * 2 * tt))))
+		if i > 2000 {
+			peak = max(peak, math.Abs(y))
+		}
+	}
+	lp32.Reset()
+	for i := range 4000 {
+		tt := float64(i) / fs
+		y := float64(lp32.Compute(float32(math.Sin(2 * math.Pi * 100 * tt))))
+		if i > 2000 {
+			stop = max(stop, math.Abs(y))
+		}
+	}
+	if math.Abs(peak-1) > 0.01 || stop > 1e-6 {
+		t.Fatalf("pass band peak %v, stop band peak %v", peak, stop)
+	}
+
+	for _, bad := range []func() (*Iir[float64], error){
+		func() (*Iir[float64], error) { return Butterworth(0, LowPassBand, fs, 100.0) },
+		func() (*Iir[float64], error) { return Butterworth(2, LowPassBand, fs, 600.0) },
+		func() (*Iir[float64], error) { return Butterworth(2, BandPassBand, fs, 100.0) },
+		func() (*Iir[float64], error) { return Butterworth(2, BandStopBand, fs, 200.0, 100) },
+		func() (*Iir[float64], error) { return Chebyshev1(2, 0, LowPassBand, fs, 100.0) },
+	} {
+		if _, err := bad(); !errors.Is(err, ErrDesign) {
+			t.Fatalf("expected ErrDesign, got %v", err)
+		}
+	}
+}
+
+func TestFir(t *testing.T) {
+	// arbitrary taps
+	avg := NewFir([]float64{0.5, 0.25, 0.25})
+	for i, want := range []float64{0.5, 1.25, 2.25, 3.25} {
+		if got := avg.Compute(float64(i + 1)); math.Abs(got-want) > 1e-12 {
+			t.Fatalf("sample %d: %v, want %v", i, got, want)
+		}
+	}
+	if NewFir[float64](nil).Compute(1) != 0 {
+		t.Fatalf("empty filter should return zero")
+	}
+
+	fs := 1000.0
+	cases := []struct {
+		name       string
+		band       Band
+		window     Window
+		cutoff     []float64
+		pass, stop []float64
+		atten      float64
+	}{
+		{"hamming lp", LowPassBand, Hamming, []float64{100}, []float64{0}, []float64{180, 300, 450}, -50},
+		{"hann hp", HighPassBand, Hann, []float64{200}, []float64{500}, []float64{0, 60, 120}, -40},
+		{"blackman bp", BandPassBand, Blackman, []float64{150, 250}, []float64{200}, []float64{0, 50, 350, 500}, -70},
+		{"kaiser bs", BandStopBand, Kaiser(KaiserBeta(80)), []float64{150, 250}, []float64{0, 500}, []float64{200}, -75},
+	}
+	for _, tc := range cases {
+		f, err := WindowedSinc(101, tc.band, tc.window, fs, tc.cutoff...)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for _, freq := range tc.pass {
+			if g := f.Gain(freq, fs); math.Abs(g) > 0.01 {
+				t.Fatalf("%s pass band %v hz: %v db", tc.name, freq, g)
+			}
+		}
+		for _, freq := range tc.stop {
+			if g := f.Gain(freq, fs); g > tc.atten {
+				t.Fatalf("%s stop band %v hz: %v db", tc.name, freq, g)
+			}
+		}
+		for _, freq := range tc.cutoff {
+			if tc.band == LowPassBand || tc.band == HighPassBand {
+				if g := f.Gain(freq, fs); math.Abs(g+6.02) > 0.1 {
+					t.Fatalf("%s cutoff %v hz: %v db", tc.name, freq, g)
+				}
+			}
+		}
+
+		// linear phase
+		h := f.Taps()
+		for i := range h {
+			if math.Abs(h[i]-h[len(h)-1-i]) > 1e-15 {
+				t.Fatalf("%s taps not symmetric", tc.name)
+			}
+		}
+	}
+
+	// block processing matches sample by sample processing across calls
+	a, _ := WindowedSinc(129, LowPassBand, Hamming, fs, 50.0)
+	b := NewFir(a.Taps())
+	if a.Delay() != 64 {
+		t.Fatalf("delay %v", a.Delay())
+	}
+	x := make([]float64, 3000)
+	for i := range x {
+		x[i] = math.Sin(0.01*float64(i)) + 0.3*math.Sin(1.7*float64(i))
+	}
+	var got []float64
+	got = append(got, a.ProcessSlice(x[:1000])...)
+	got = append(got, a.ProcessSlice(x[1000:1010])...)
+	got = append(got, a.ProcessSlice(x[1010:2500])...)
+	for _, v := range x[2500:] {
+		got = append(got, a.Compute(v))
+	}
+	for i, v := range x {
+		if want := b.Compute(v); math.Abs(got[i]-want) > 1e-9 {
+			t.Fatalf("sample %d: %v, want %v", i, got[i], want)
+		}
+	}
+
+	if _, err := WindowedSinc(100, HighPassBand, Hamming, fs, 100.0); !errors.Is(err, ErrDesign) {
+		t.Fatalf("expected ErrDesign for even high pass, got %v", err)
+	}
+	if _, err := WindowedSinc(0, LowPassBand, nil, fs, 100.0); !errors.Is(err, ErrDesign) {
+		t.Fatalf("expected ErrDesign for no taps, got %v", err)
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	// brute force quantile with linear interpolation
+	quantile := func(w []float64, q float64) float64 {
+		s := slices.Clone(w)
+		slices.Sort(s)
+		h := q * float64(len(s)-1)
+		i := int(math.Floor(h))
+		if i+1 >= len(s) {
+			return s[i]
+		}
+		return s[i] + (h-float64(i))*(s[i+1]-s[i])
+	}
+
+	input := func(i int) float64 { return float64((i*7919)%101) - 0.25*float64(i%13) }
+
+	for _, window := range []int{1, 2, 5, 8, 31} {
+		for _, q := range []float64{0, 0.1, 0.5, 0.9, 1} {
+			f := NewQuantile[float64](window, q)
+			var w []float64
+			for i := range 300 {
+				x := input(i)
+				w = append(w, x)
+				if len(w) > window {
+					w = w[1:]
+				}
+				if got, want := f.Compute(x), quantile(w, q); math.Abs(got-want) > 1e-12 {
+					t.Fatalf("window %d q %v sample %d: %v, want %v", window, q, i, got, want)
+				}
+			}
+		}
+
+		lo, hi := NewMin[float64](window), NewMax[float64](window)
+		med := NewMedian[float64](window)
+		var w []float64
+		for i := range 300 {
+			x := input(i)
+			w = append(w, x)
+			if len(w) > window {
+				w = w[1:]
+			}
+			if got := lo.Compute(x); got != slices.Min(w) {
+				t.Fatalf("window %d sample %d: min %v, want %v", window, i, got, slices.Min(w))
+			}
+			if got := hi.Compute(x); got != slices.Max(w) {
+				t.Fatalf("window %d sample %d: max %v, want %v", window, i, got, slices.Max(w))
+			}
+			if got, want := med.Compute(x), quantile(w, 0.5); math.Abs(got-want) > 1e-12 {
+				t.Fatalf("window %d sample %d: median %v, want %v", window, i, got, want)
+			}
+		}
+	}
+
+	// integer median of an even window truncates the average
+	m := NewMedian[int](4)
+	for i, want := range []int{3, 2, 3, 4} {
+		if got := m.Compute([]int{3, 2, 8, 5}[i]); got != want {
+			t.Fatalf("int median sample %d: %v, want %v", i, got, want)
+		}
+	}
+
+	// steady state is allocation free
+	f := NewQuantile[float64](501, 0.5)
+	g := NewMax[float64](501)
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		f.Compute(input(i))
+		g.Compute(input(i))
+		i++
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations per sample", allocs)
+	}
+
+	if NewQuantile[float64](0, 0.5).Compute(1) != 0 || NewMin[int](0).Compute(1) != 0 {
+		t.Fatalf("empty filter should return zero")
+	}
+}
+
+func TestWindowStatistics(t *testing.T) {
+	// two pass reference
+	reference := func(w []float64) (mean, variance, rms float64) {
+		for _, x := range w {
+			mean += x
+			rms += x * x
+		}
+		mean /= float64(len(w))
+		for _, x := range w {
+			variance += (x - mean) * (x - mean)
+		}
+		return mean, variance / float64(len(w)), math.Sqrt(rms / float64(len(w)))
+	}
+	close := func(a, b, tol float64) bool { return math.Abs(a-b) <= tol*max(1, math.Abs(b)) }
+
+	input := func(i int) float64 { return float64((i*7919)%101) - 0.25*float64(i%13) }
+
+	for _, window := range []int{1, 3, 16} {
+		mean, variance := NewMean[float64](window), NewVariance[float64](window)
+		sample, std := NewVariance[float64](window).WithSample(true), NewStd[float64](window)
+		rms, stats := NewRms[float64](window), NewStats[float64](window)
+
+		var w []float64
+		for i := range 200 {
+			x := input(i)
+			w = append(w, x)
+			if len(w) > window {
+				w = w[1:]
+			}
+			m, v, r := reference(w)
+			s := 0.0
+			if len(w) > 1 {
+				s = v * float64(len(w)) / float64(len(w)-1)
+			}
+
+			stats.Add(x)
+			for _, check := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", mean.Compute(x), m},
+				{"variance", variance.Compute(x), v},
+				{"sample variance", sample.Compute(x), s},
+				{"std", std.Compute(x), math.Sqrt(v)},
+				{"rms", rms.Compute(x), r},
+				{"stats mean", stats.Mean(), m},
+				{"stats variance", stats.Variance(), v},
+				{"stats sample variance", stats.SampleVariance(), s},
+				{"stats std", stats.Std(), math.Sqrt(v)},
+				{"stats rms", stats.Rms(), r},
+				{"stats min", stats.Min(), slices.Min(w)},
+				{"stats max", stats.Max(), slices.Max(w)},
+				{"stats count", float64(stats.Count()), float64(len(w))},
+			} {
+				if !close(check.got, check.want, 1e-9) {
+					t.Fatalf("window %d sample %d: %s %v, want %v", window, i, check.name, check.got, check.want)
+				}
+			}
+		}
+	}
+
+	// long run with a large offset and small variations
+	const window = 64
+	mean, variance := NewMean[float64](window), NewVariance[float64](window)
+	noise := func(i int) float64 { return 1e6 + 1e-3*math.Sin(0.37*float64(i)) }
+	var m, v float64
+	for i := range 2_000_000 {
+		m, v = mean.Compute(noise(i)), variance.Compute(noise(i))
+	}
+	w := make([]float64, window)
+	for j := range w {
+		w[j] = noise(2_000_000 - window + j)
+	}
+	wantM, wantV, _ := reference(w)
+	if math.Abs(m-wantM) > 1e-6 || math.Abs(v-wantV) > 1e-5*wantV {
+		t.Fatalf("long run: mean %v variance %v, want %v %v", m, v, wantM, wantV)
+	}
+
+	// steady state is allocation free
+	stats := NewStats[float64](501)
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		mean.Compute(input(i))
+		variance.Compute(input(i))
+		stats.Add(input(i))
+		i++
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations per sample", allocs)
+	}
+
+	if NewVariance[float64](0).Compute(1) != 0 || NewRms[float64](-1).Compute(1) != 0 {
+		t.Fatalf("empty filter should return zero")
+	}
+}
+
+func TestOutlier(t *testing.T) {
+	spikes := map[int]float64{40: 25, 90: -30, 91: 20, 150: 40}
+	// slow drift with uniform noise, which never exceeds 1.8 standard deviations
+	signal := func(i int) float64 { return 0.5*math.Sin(0.02*float64(i)) + 0.05*(float64((i*7919)%101)/100-0.5) }
+	input := func(i int) float64 { return signal(i) + spikes[i] }
+
+	// every spike is rejected and replaced by a value close to the signal
+	hampel := NewHampel(9, 3.0)
+	gate := NewZScoreGate(20, 4.0).WithMad(true)
+	plain := NewZScoreGate(20, 4.0)
+	for i := range 200 {
+		x := input(i)
+		_, isSpike := spikes[i]
+
+		y, rejected := hampel.Compute(x)
+		if rejected != isSpike {
+			t.Fatalf("hampel sample %d: rejected %v", i, rejected)
+		}
+		if math.Abs(y-signal(i)) > 0.2 {
+			t.Fatalf("hampel sample %d: %v, want about %v", i, y, signal(i))
+		}
+
+		y, rejected = gate.Compute(x)
+		if rejected != isSpike {
+			t.Fatalf("mad gate sample %d: rejected %v", i, rejected)
+		}
+		if math.Abs(y-signal(i)) > 0.2 {
+			t.Fatalf("mad gate sample %d: %v, want about %v", i, y, signal(i))
+		}
+
+		// the first spike in a clean window is caught by the plain z-score too
+		if _, rejected = plain.Compute(x); i == 40 && !rejected {
+			t.Fatalf("z-score gate missed the spike at %d", i)
+		}
+	}
+
+	// a step is accepted once it dominates the window
+	hampel.Reset()
+	accepted := -1
+	for i := range 20 {
+		x := 0.0
+		if i >= 10 {
+			x = 5
+		}
+		if _, rejected := hampel.Compute(x); i >= 10 && !rejected && accepted < 0 {
+			accepted = i
+		}
+	}
+	if accepted < 10 || accepted > 15 {
+		t.Fatalf("hampel accepted the step at %d", accepted)
+	}
+
+	// gated scalar kalman filter ignores the spike that drags the plain one
+	gated := NewKalmanScalar(1e-4, 0.01, 1, 0.0).WithGate(10.83)
+	ungated := NewKalmanScalar(1e-4, 0.01, 1, 0.0)
+	for i := range 100 {
+		z := 1 + 0.1*math.Sin(2.1*float64(i))
+		if i == 50 {
+			z = 100
+		}
+		g, u := gated.Compute(z), ungated.Compute(z)
+		if gated.Rejected() != (i == 50) {
+			t.Fatalf("gated kalman sample %d: rejected %v with nis %v", i, gated.Rejected(), gated.Nis())
+		}
+		if i == 50 && (math.Abs(g-1) > 0.1 || math.Abs(u-1) < 1) {
+			t.Fatalf("spike: gated %v, ungated %v", g, u)
+		}
+	}
+
+	// gated matrix kalman filter leaves the estimate unchanged
+	k, _ := NewKalman([]float64{0}, [][]float64{{1}})
+	k.Transition([][]float64{{1}}, [][]float64{{0.01}})
+	k.Measurement([][]float64{{1}}, [][]float64{{0.1}})
+	var est Estimator[float64] = k
+	est.Gate(6.63)
+	est.Predict(nil, 1)
+	if err := est.Update([]float64{0.5}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	before := est.State()[0]
+	est.Predict(nil, 1)
+	if err := est.Update([]float64{50}); !errors.Is(err, ErrOutlier) {
+		t.Fatalf("expected ErrOutlier, got %v", err)
+	}
+	if est.State()[0] != before || est.Nis() < 6.63 {
+		t.Fatalf("rejected update changed the state to %v, nis %v", est.State()[0], est.Nis())
+	}
+
+	// spike suppressor holds through short spikes and accepts a lasting step
+	s := NewSpikeSuppressor(10.0, 2)
+	want := []struct {
+		x, y     float64
+		rejected bool
+	}{
+		{0, 0, false},
+		{0.05, 0.05, false},
+		{5, 0.05, true},
+		{0.1, 0.1, false},
+		{5, 0.1, true},
+		{5, 0.1, true},
+		{5, 5, false},
+		{5.05, 5.05, false},
+	}
+	for i, w := range want {
+		if y, rejected := s.Compute(w.x, 0.01); y != w.y || rejected != w.rejected {
+			t.Fatalf("suppressor sample %d: %v %v, want %v %v", i, y, rejected, w.y, w.rejected)
+		}
+	}
+	slew := NewSpikeSuppressor(10.0, 5).WithSlew(true)
+	slew.Compute(0, 0.01)
+	if y, rejected := slew.Compute(5, 0.01); !rejected || math.Abs(y-0.1) > 1e-12 {
+		t.Fatalf("slew: %v %v", y, rejected)
+	}
+
+	// the mad window is allocation free
+	i := 0
+	allocs := testing.AllocsPerRun(1000, func() {
+		hampel.Compute(input(i))
+		i++
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations per sample", allocs)
+	}
+}
@@ -0,0 +1,332 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// filters with at least this many taps use fft overlap add in ProcessSlice
+const firFftTaps = 64
+
+// weight the taps of a windowed sinc design, i runs from 0 to n-1
+type Window func(i, n int) float64
+
+// hamming window, first side lobe at -43 db
+func Hamming(i, n int) float64 {
+	if n == 1 {
+		return 1
+	}
+	return 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+}
+
+// hann window, first side lobe at -31 db with fast side lobe decay
+func Hann(i, n int) float64 {
+	if n == 1 {
+		return 1
+	}
+	return 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+}
+
+// blackman window, first side lobe at -58 db with a wider main lobe
+func Blackman(i, n int) float64 {
+	if n == 1 {
+		return 1
+	}
+	x := 2 * math.Pi * float64(i) / float64(n-1)
+	return 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+}
+
+// return a kaiser window with shape parameter beta, trading main lobe width for side lobe level
+//
+// beta = 0 is rectangular, KaiserBeta gives beta for a target stop band attenuation
+func Kaiser(beta float64) Window {
+	den := besselI0(beta)
+	return func(i, n int) float64 {
+		if n == 1 {
+			return 1
+		}
+		r := 2*float64(i)/float64(n-1) - 1
+		return besselI0(beta*math.Sqrt(max(0, 1-r*r))) / den
+	}
+}
+
+// return the kaiser beta for a stop band attenuation in db
+func KaiserBeta(attenuation float64) float64 {
+	switch {
+	case attenuation > 50:
+		return 0.1102 * (attenuation - 8.7)
+	case attenuation >= 21:
+		return 0.5842*math.Pow(attenuation-21, 0.4) + 0.07886*(attenuation-21)
+	default:
+		return 0
+	}
+}
+
+// return the modified bessel function of the first kind of order zero
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	q := x * x / 4
+	for k := 1; k < 500; k++ {
+		term *= q / float64(k*k)
+		sum += term
+		if term < 1e-17*sum {
+			break
+		}
+	}
+	return sum
+}
+
+// filter a signal with a finite impulse response
+//
+//	y_k = sum_j taps[j] * x_{k-j}
+//
+// the history is kept in a circular buffer, so Compute never shifts samples
+//
+// taps and state are stored as float64 whatever T is
+//
+// this type is not safe for concurrent use
+type Fir[T c.Float] struct {
+	taps []float64
+
+	// history stored twice so the newest n samples are always contiguous, newest first
+	buf []float64
+	pos int
+
+	// fft of the taps for ProcessSlice, computed on first use
+	spectrum []complex128
+}
+
+// create an fir filter from its taps
+//
+// if taps is empty, it creates an empty filter that returns zero
+//
+// time: O(n) where n is the number of taps
+func NewFir[T c.Float](taps []T) *Fir[T] {
+	return &Fir[T]{
+		taps: toFloats(taps),
+		buf:  make([]float64, 2*len(taps)),
+	}
+}
+
+// design a linear phase fir filter by the windowed sinc method
+//
+// fs is the sample rate and cutoff the -6 db frequency in hz, one for low and high pass and
+// the lower and upper edges for band pass and band stop
+//
+// the taps are scaled for unit gain at dc for low pass and band stop, at nyquist for high
+// pass and at the centre of the band for band pass
+//
+// a nil window is rectangular
+//
+// return ErrDesign if taps is not positive, is even for high pass and band stop, which
+// need a zero at nyquist, or a cutoff is not strictly between 0 and fs/2
+//
+// time: O(n) where n is the number of taps
+func WindowedSinc[T c.Float](taps int, band Band, window Window, fs T, cutoff ...T) (*Fir[T], error) {
+	edges, ok := bandEdges(band, float64(fs), cutoff)
+	if taps < 1 || !ok || ((band == HighPassBand || band == BandStopBand) && taps%2 == 0) {
+		return nil, ErrDesign
+	}
+
+	n := taps
+	mid := float64(n-1) / 2
+
+	// ideal low pass with cutoff f, in cycles per sample
+	lowPass := func(i int, f float64) float64 {
+		x := float64(i) - mid
+		if x == 0 {
+			return 2 * f
+		}
+		return math.Sin(2*math.Pi*f*x) / (math.Pi * x)
+	}
+	delta := func(i int) float64 {
+		if float64(i) == mid {
+			return 1
+		}
+		return 0
+	}
+
+	f1 := edges[0] / float64(fs)
+	h := make([]float64, n)
+	for i := range h {
+		switch band {
+		case LowPassBand:
+			h[i] = lowPass(i, f1)
+		case HighPassBand:
+			h[i] = delta(i) - lowPass(i, f1)
+		case BandPassBand:
+			h[i] = lowPass(i, edges[1]/float64(fs)) - lowPass(i, f1)
+		case BandStopBand:
+			h[i] = delta(i) - lowPass(i, edges[1]/float64(fs)) + lowPass(i, f1)
+		}
+		if window != nil {
+			h[i] *= window(i, n)
+		}
+	}
+
+	// unit gain at the reference frequency
+	ref := 0.0
+	switch band {
+	case HighPassBand:
+		ref = 0.5
+	case BandPassBand:
+		ref = (edges[0] + edges[1]) / 2 / float64(fs)
+	}
+	if g := cmplx.Abs(tapResponse(h, 2*math.Pi*ref)); g > 0 {
+		for i := range h {
+			h[i] /= g
+		}
+	}
+
+	return &Fir[T]{taps: h, buf: make([]float64, 2*n)}, nil
+}
+
+// return a copy of the taps
+//
+// time: O(n) where n is the number of taps
+func (f *Fir[T]) Taps() []T {
+	return fromFloats[T](f.taps, len(f.taps))
+}
+
+// return the group delay in samples of a linear phase filter, (n-1)/2
+//
+// time: O(1)
+func (f *Fir[T]) Delay() T {
+	return T(max(0, len(f.taps)-1)) / 2
+}
+
+// reset internal state
+//
+// time: O(n) where n is the number of taps
+func (f *Fir[T]) Reset() {
+	clear(f.buf)
+	f.pos = 0
+}
+
+// compute the filtered value
+//
+// time: O(n) where n is the number of taps
+func (f *Fir[T]) Compute(x T) T {
+	n := len(f.taps)
+	if n == 0 {
+		return 0
+	}
+
+	f.pos--
+	if f.pos < 0 {
+		f.pos = n - 1
+	}
+	f.buf[f.pos] = float64(x)
+	f.buf[f.pos+n] = float64(x)
+
+	y := 0.0
+	hist := f.buf[f.pos : f.pos+n]
+	for j, h := range f.taps {
+		y += h * hist[j]
+	}
+	return T(y)
+}
+
+// filter a block of samples, continuing from and updating the same state as Compute
+//
+// filters with many taps use fft overlap add, which is much cheaper than direct convolution
+// for long blocks, the output matches calling Compute on each sample up to rounding
+//
+// time: O(m log n) with fft, O(m*n) otherwise, where m is the block length and n the number of taps
+func (f *Fir[T]) ProcessSlice(x []T) []T {
+	n := len(f.taps)
+	out := make([]T, len(x))
+	if n == 0 || len(x) == 0 {
+		return out
+	}
+
+	if n < firFftTaps || len(x) < n {
+		for i, v := range x {
+			out[i] = f.Compute(v)
+		}
+		return out
+	}
+
+	// previous n-1 inputs, oldest first, followed by the block
+	s := make([]float64, n-1+len(x))
+	for i := range n - 1 {
+		s[i] = f.buf[f.pos+n-2-i]
+	}
+	for i, v := range x {
+		s[n-1+i] = float64(v)
+	}
+
+	y := f.overlapAdd(s)
+	for i := range out {
+		out[i] = T(y[n-1+i])
+	}
+
+	// the last n inputs become the history, newest first
+	f.pos = 0
+	for j := range n {
+		v := s[len(s)-1-j]
+		f.buf[j], f.buf[j+n] = v, v
+	}
+	return out
+}
+
+// return the first len(s) samples of the convolution of s with the taps
+func (f *Fir[T]) overlapAdd(s []float64) []float64 {
+	n := len(f.taps)
+	size := nextPow2(4 * n)
+	block := size - n + 1
+
+	if len(f.spectrum) != size {
+		f.spectrum = make([]complex128, size)
+		for i, h := range f.taps {
+			f.spectrum[i] = complex(h, 0)
+		}
+		fft(f.spectrum, false)
+	}
+
+	out := make([]float64, len(s)+size)
+	buf := make([]complex128, size)
+	for start := 0; start < len(s); start += block {
+		end := min(start+block, len(s))
+
+		clear(buf)
+		for i, v := range s[start:end] {
+			buf[i] = complex(v, 0)
+		}
+		fft(buf, false)
+		for i := range buf {
+			buf[i] *= f.spectrum[i]
+		}
+		fft(buf, true)
+
+		for i, v := range buf {
+			out[start+i] += real(v)
+		}
+	}
+	return out[:len(s)]
+}
+
+// return the complex frequency response at frequency freq for a sample rate fs, both in hz
+//
+// time: O(n) where n is the number of taps
+func (f *Fir[T]) Response(freq, fs T) complex128 {
+	return tapResponse(f.taps, 2*math.Pi*float64(freq)/float64(fs))
+}
+
+// return the magnitude response in decibels at frequency freq for a sample rate fs, both in hz
+//
+// time: O(n) where n is the number of taps
+func (f *Fir[T]) Gain(freq, fs T) T {
+	return T(20 * math.Log10(cmplx.Abs(f.Response(freq, fs))))
+}
+
+// return the response of taps h at normalised angular frequency w in rad/sample
+func tapResponse(h []float64, w float64) complex128 {
+	var out complex128
+	for i, v := range h {
+		out += complex(v, 0) * cmplx.Exp(complex(0, -w*float64(i)))
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+	"slices"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// store the coefficients of a second order section
+//
+//	H(z) = (B0 + B1*z^-1 + B2*z^-2) / (1 + A1*z^-1 + A2*z^-2)
+//
+// a first order section has B2 = A2 = 0
+type Biquad struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+}
+
+// return the frequency response of the section at normalised angular frequency w in rad/sample
+func (s Biquad) response(w float64) complex128 {
+	z1 := cmplx.Exp(complex(0, -w))
+	z2 := z1 * z1
+	num := complex(s.B0, 0) + complex(s.B1, 0)*z1 + complex(s.B2, 0)*z2
+	den := 1 + complex(s.A1, 0)*z1 + complex(s.A2, 0)*z2
+	return num / den
+}
+
+// filter a signal with a cascade of second order sections
+//
+// each section runs in transposed direct form ii, which keeps high order designs
+// numerically stable where a single high order difference equation would not be
+//
+// coefficients and state are stored as float64 whatever T is
+//
+// this type is not safe for concurrent use
+type Iir[T c.Float] struct {
+	sections []Biquad
+	state    [][2]float64
+}
+
+// create an iir filter from its second order sections, applied in order
+//
+// time: O(n) where n is the number of sections
+func NewIir[T c.Float](sections []Biquad) *Iir[T] {
+	return &Iir[T]{
+		sections: slices.Clone(sections),
+		state:    make([][2]float64, len(sections)),
+	}
+}
+
+// return a copy of the second order sections
+//
+// time: O(n) where n is the number of sections
+func (f *Iir[T]) Sections() []Biquad {
+	return slices.Clone(f.sections)
+}
+
+// reset internal state
+//
+// time: O(n) where n is the number of sections
+func (f *Iir[T]) Reset() {
+	clear(f.state)
+}
+
+// compute the filtered value
+//
+// time: O(n) where n is the number of sections
+func (f *Iir[T]) Compute(x T) T {
+	v := float64(x)
+	for i, s := range f.sections {
+		st := &f.state[i]
+		y := s.B0*v + st[0]
+		st[0] = s.B1*v - s.A1*y + st[1]
+		st[1] = s.B2*v - s.A2*y
+		v = y
+	}
+	return T(v)
+}
+
+// return the complex frequency response at frequency freq for a sample rate fs, both in hz
+//
+// time: O(n) where n is the number of sections
+func (f *Iir[T]) Response(freq, fs T) complex128 {
+	w := 2 * math.Pi * float64(freq) / float64(fs)
+	h := complex(1, 0)
+	for _, s := range f.sections {
+		h *= s.response(w)
+	}
+	return h
+}
+
+// return the magnitude response in decibels at frequency freq for a sample rate fs, both in hz
+//
+// time: O(n) where n is the number of sections
+func (f *Iir[T]) Gain(freq, fs T) T {
+	return T(20 * math.Log10(cmplx.Abs(f.Response(freq, fs))))
+}
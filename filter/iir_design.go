@@ -0,0 +1,381 @@
+package filter
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"slices"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// returned when a filter design has an invalid order, ripple or cutoff frequency
+var ErrDesign = errors.New("filter: invalid filter design")
+
+// select the frequency band an iir design keeps
+type Band int
+
+const (
+	// keep frequencies below the cutoff
+	LowPassBand Band = iota
+
+	// keep frequencies above the cutoff
+	HighPassBand
+
+	// keep frequencies between two cutoffs
+	BandPassBand
+
+	// reject frequencies between two cutoffs
+	BandStopBand
+)
+
+// return the name of the band
+func (b Band) String() string {
+	switch b {
+	case LowPassBand:
+		return "low pass"
+	case HighPassBand:
+		return "high pass"
+	case BandPassBand:
+		return "band pass"
+	case BandStopBand:
+		return "band stop"
+	default:
+		return "unknown"
+	}
+}
+
+// design a butterworth filter, maximally flat in the pass band
+//
+// order is the order of the low pass prototype, band pass and band stop designs have twice
+// as many poles
+//
+// fs is the sample rate and cutoff the -3 db frequency in hz, one for low and high pass and
+// the lower and upper edges for band pass and band stop
+//
+// return ErrDesign if the order is not positive or a cutoff is not strictly between 0 and fs/2
+//
+// time: O(order^2)
+func Butterworth[T c.Float](order int, band Band, fs T, cutoff ...T) (*Iir[T], error) {
+	if order < 1 {
+		return nil, ErrDesign
+	}
+
+	p := make([]complex128, order)
+	for i := range p {
+		m := float64(2*i - order + 1)
+		p[i] = -cmplx.Exp(complex(0, math.Pi*m/float64(2*order)))
+	}
+
+	return design[T](zpk{p: p, k: 1}, band, float64(fs), cutoff)
+}
+
+// design a chebyshev type i filter, with ripple db of equiripple in the pass band and a
+// steeper transition than a butterworth filter of the same order
+//
+// order is the order of the low pass prototype, band pass and band stop designs have twice
+// as many poles
+//
+// fs is the sample rate and cutoff the pass band edge in hz, where the gain first drops
+// below -ripple db, one for low and high pass and the lower and upper edges for band pass
+// and band stop
+//
+// return ErrDesign if the order or ripple is not positive or a cutoff is not strictly
+// between 0 and fs/2
+//
+// time: O(order^2)
+func Chebyshev1[T c.Float](order int, ripple T, band Band, fs T, cutoff ...T) (*Iir[T], error) {
+	if order < 1 || ripple <= 0 {
+		return nil, ErrDesign
+	}
+
+	eps := math.Sqrt(math.Pow(10, float64(ripple)/10) - 1)
+	mu := math.Asinh(1/eps) / float64(order)
+
+	p := make([]complex128, order)
+	k := complex(1, 0)
+	for i := range p {
+		theta := math.Pi * float64(2*i-order+1) / float64(2*order)
+		p[i] = -cmplx.Sinh(complex(mu, theta))
+		k *= -p[i]
+	}
+
+	// even orders start the ripple at its bottom, so the dc gain is -ripple db
+	gain := real(k)
+	if order%2 == 0 {
+		gain /= math.Sqrt(1 + eps*eps)
+	}
+
+	return design[T](zpk{p: p, k: gain}, band, float64(fs), cutoff)
+}
+
+// store a filter as its zeros, poles and gain
+type zpk struct {
+	z, p []complex128
+	k    float64
+}
+
+// transform an analog low pass prototype with unit cutoff into a digital filter
+func design[T c.Float](proto zpk, band Band, fs float64, cutoff []T) (*Iir[T], error) {
+	edges, ok := bandEdges(band, fs, cutoff)
+	if !ok {
+		return nil, ErrDesign
+	}
+
+	// prewarp the edges so the bilinear transform puts them at the requested frequencies
+	w := make([]float64, len(edges))
+	for i, f := range edges {
+		w[i] = 2 * fs * math.Tan(math.Pi*f/fs)
+	}
+
+	var analog zpk
+	switch band {
+	case LowPassBand:
+		analog = proto.lowPass(w[0])
+	case HighPassBand:
+		analog = proto.highPass(w[0])
+	case BandPassBand:
+		analog = proto.bandPass(math.Sqrt(w[0]*w[1]), w[1]-w[0])
+	case BandStopBand:
+		analog = proto.bandStop(math.Sqrt(w[0]*w[1]), w[1]-w[0])
+	default:
+		return nil, ErrDesign
+	}
+
+	return NewIir[T](analog.bilinear(fs).sections()), nil
+}
+
+// return the cutoff frequencies of a band as float64
+//
+// return false if the band does not have one cutoff for low and high pass and two increasing
+// ones for band pass and band stop, or a cutoff is not strictly between 0 and fs/2
+func bandEdges[T c.Float](band Band, fs float64, cutoff []T) ([]float64, bool) {
+	want := 1
+	if band == BandPassBand || band == BandStopBand {
+		want = 2
+	}
+	if len(cutoff) != want || !(fs > 0) {
+		return nil, false
+	}
+
+	edges := make([]float64, want)
+	for i, f := range cutoff {
+		edges[i] = float64(f)
+		if !(edges[i] > 0 && edges[i] < fs/2) || (i > 0 && edges[i] <= edges[i-1]) {
+			return nil, false
+		}
+	}
+	return edges, true
+}
+
+// scale the cutoff of a low pass prototype to wo
+func (f zpk) lowPass(wo float64) zpk {
+	out := zpk{k: f.k * math.Pow(wo, float64(len(f.p)-len(f.z)))}
+	for _, z := range f.z {
+		out.z = append(out.z, z*complex(wo, 0))
+	}
+	for _, p := range f.p {
+		out.p = append(out.p, p*complex(wo, 0))
+	}
+	return out
+}
+
+// turn a low pass prototype into a high pass filter with cutoff wo
+func (f zpk) highPass(wo float64) zpk {
+	out := zpk{k: f.k * real(prodNeg(f.z)/prodNeg(f.p))}
+	for _, z := range f.z {
+		out.z = append(out.z, complex(wo, 0)/z)
+	}
+	for _, p := range f.p {
+		out.p = append(out.p, complex(wo, 0)/p)
+	}
+
+	// zeros at the origin for every pole without a zero
+	for range len(f.p) - len(f.z) {
+		out.z = append(out.z, 0)
+	}
+	return out
+}
+
+// turn a low pass prototype into a band pass filter centred at wo with bandwidth bw
+func (f zpk) bandPass(wo, bw float64) zpk {
+	out := zpk{k: f.k * math.Pow(bw, float64(len(f.p)-len(f.z)))}
+	split := func(v complex128) (complex128, complex128) {
+		v *= complex(bw/2, 0)
+		d := cmplx.Sqrt(v*v - complex(wo*wo, 0))
+		return v + d, v - d
+	}
+	for _, z := range f.z {
+		a, b := split(z)
+		out.z = append(out.z, a, b)
+	}
+	for _, p := range f.p {
+		a, b := split(p)
+		out.p = append(out.p, a, b)
+	}
+	for range len(f.p) - len(f.z) {
+		out.z = append(out.z, 0)
+	}
+	return out
+}
+
+// turn a low pass prototype into a band stop filter centred at wo with bandwidth bw
+func (f zpk) bandStop(wo, bw float64) zpk {
+	out := zpk{k: f.k * real(prodNeg(f.z)/prodNeg(f.p))}
+	split := func(v complex128) (complex128, complex128) {
+		v = complex(bw/2, 0) / v
+		d := cmplx.Sqrt(v*v - complex(wo*wo, 0))
+		return v + d, v - d
+	}
+	for _, z := range f.z {
+		a, b := split(z)
+		out.z = append(out.z, a, b)
+	}
+	for _, p := range f.p {
+		a, b := split(p)
+		out.p = append(out.p, a, b)
+	}
+
+	// a notch pair at the centre for every pole without a zero
+	for range len(f.p) - len(f.z) {
+		out.z = append(out.z, complex(0, wo), complex(0, -wo))
+	}
+	return out
+}
+
+// map an analog filter to the z plane with the bilinear transform at sample rate fs
+func (f zpk) bilinear(fs float64) zpk {
+	fs2 := complex(2*fs, 0)
+
+	num, den := complex(1, 0), complex(1, 0)
+	out := zpk{}
+	for _, z := range f.z {
+		out.z = append(out.z, (fs2+z)/(fs2-z))
+		num *= fs2 - z
+	}
+	for _, p := range f.p {
+		out.p = append(out.p, (fs2+p)/(fs2-p))
+		den *= fs2 - p
+	}
+	out.k = f.k * real(num/den)
+
+	// zeros at infinity map to nyquist
+	for range len(f.p) - len(f.z) {
+		out.z = append(out.z, -1)
+	}
+	return out
+}
+
+// group a digital filter with as many zeros as poles into second order sections
+//
+// conjugate poles share a section, sections are ordered from the poles furthest from the
+// unit circle to the closest, and each takes the nearest remaining zeros
+//
+// the gain is spread evenly over the sections to keep intermediate values in range
+func (f zpk) sections() []Biquad {
+	poles := pairRoots(f.p)
+	zeros := pairRoots(f.z)
+
+	slices.SortStableFunc(poles, func(a, b []complex128) int {
+		da, db := 1-cmplx.Abs(a[0]), 1-cmplx.Abs(b[0])
+		switch {
+		case da > db:
+			return -1
+		case da < db:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	n := len(poles)
+	g := math.Pow(math.Abs(f.k), 1/float64(n))
+	out := make([]Biquad, n)
+	for i, p := range poles {
+		// nearest remaining zero group, of the same size when there is one
+		best := -1
+		for j, z := range zeros {
+			if best >= 0 && (len(zeros[best]) == len(p)) != (len(z) == len(p)) {
+				if len(z) == len(p) {
+					best = j
+				}
+				continue
+			}
+			if best < 0 || cmplx.Abs(z[0]-p[0]) < cmplx.Abs(zeros[best][0]-p[0]) {
+				best = j
+			}
+		}
+		z := zeros[best]
+		zeros = slices.Delete(zeros, best, best+1)
+
+		b0, b1, b2 := rootPoly(z)
+		_, a1, a2 := rootPoly(p)
+		out[i] = Biquad{B0: g * b0, B1: g * b1, B2: g * b2, A1: a1, A2: a2}
+	}
+
+	if f.k < 0 {
+		out[0].B0, out[0].B1, out[0].B2 = -out[0].B0, -out[0].B1, -out[0].B2
+	}
+	return out
+}
+
+// group roots into conjugate pairs and pairs of real roots, with at most one single real root
+//
+// real roots are paired smallest with largest, so a band pass section gets one zero at
+// dc and one at nyquist
+func pairRoots(roots []complex128) [][]complex128 {
+	const tol = 1e-10
+
+	var reals, upper []complex128
+	for _, r := range roots {
+		switch {
+		case math.Abs(imag(r)) <= tol*max(1, cmplx.Abs(r)):
+			reals = append(reals, complex(real(r), 0))
+		case imag(r) > 0:
+			upper = append(upper, r)
+		}
+	}
+
+	var out [][]complex128
+	for _, r := range upper {
+		out = append(out, []complex128{r, cmplx.Conj(r)})
+	}
+
+	slices.SortFunc(reals, func(a, b complex128) int {
+		switch {
+		case real(a) < real(b):
+			return -1
+		case real(a) > real(b):
+			return 1
+		default:
+			return 0
+		}
+	})
+	for len(reals) >= 2 {
+		out = append(out, []complex128{reals[0], reals[len(reals)-1]})
+		reals = reals[1 : len(reals)-1]
+	}
+	if len(reals) == 1 {
+		out = append(out, reals)
+	}
+	return out
+}
+
+// return the coefficients of the monic polynomial in z^-1 with one or two roots
+//
+//	(1 - r1*z^-1)*(1 - r2*z^-1) = 1 + c1*z^-1 + c2*z^-2
+func rootPoly(r []complex128) (c0, c1, c2 float64) {
+	if len(r) == 1 {
+		return 1, -real(r[0]), 0
+	}
+	return 1, -real(r[0] + r[1]), real(r[0] * r[1])
+}
+
+// return the product of -v over all values
+func prodNeg(v []complex128) complex128 {
+	out := complex(1, 0)
+	for _, x := range v {
+		out *= -x
+	}
+	return out
+}
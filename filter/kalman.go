@@ -0,0 +1,234 @@
+package filter
+
+import (
+	"errors"
+
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+var (
+	// returned when a matrix or vector does not match the filter dimensions
+	ErrDimension = errors.New("filter: dimension mismatch")
+
+	// returned when a covariance cannot be inverted or factorised
+	ErrSingular = errors.New("filter: singular covariance")
+
+	// returned when a measurement noise variance is not positive or a process noise variance is negative
+	ErrNoise = errors.New("filter: invalid noise variance")
+
+	// returned when an innovation gate rejects a measurement
+	ErrOutlier = errors.New("filter: measurement rejected as outlier")
+)
+
+// estimate the state of a linear system with a kalman filter
+//
+// model
+//
+//	x_k = F*x_{k-1} + B*u_k + w,  w ~ N(0, Q)
+//	z_k = H*x_k + v,              v ~ N(0, R)
+//
+// Predict and Update are separate steps, so measurements may arrive asynchronously,
+// from several sensors with their own H and R, and with a varying timestep between them
+//
+// the covariance update uses the joseph form
+//
+//	P = (I - K*H)*P*(I - K*H)^t + K*R*K^t
+//
+// which keeps P symmetric and positive semi definite under rounding
+//
+// every update records the innovation, its covariance and the normalised innovation squared
+// for consistency checking
+//
+// this type is not safe for concurrent use
+type Kalman[T c.Float] struct {
+	estimate[T]
+
+	// fixed model, or a function of dt
+	f, q  linalg.Matrix
+	model func(dt T) (f, q [][]T)
+	b     linalg.Matrix
+	h, r  linalg.Matrix
+}
+
+// create a kalman filter from the initial estimate x0 and its covariance p0
+//
+// the transition starts as the identity with zero process noise, and there is no
+// control input or default measurement model
+//
+// return ErrDimension if p0 is not len(x0) by len(x0)
+//
+// time: O(n^2)
+func NewKalman[T c.Float](x0 []T, p0 [][]T) (*Kalman[T], error) {
+	e, ok := newEstimate(x0, p0)
+	if !ok {
+		return nil, ErrDimension
+	}
+
+	return &Kalman[T]{
+		estimate: e,
+		f:        linalg.Identity(e.n),
+		q:        linalg.New(e.n, e.n),
+	}, nil
+}
+
+// use a fixed state transition f and process noise covariance q for every prediction
+//
+// return ErrDimension if f or q is not n by n
+//
+// time: O(n^2)
+func (k *Kalman[T]) Transition(f, q [][]T) error {
+	fm, qm, ok := k.square(f, q)
+	if !ok {
+		return ErrDimension
+	}
+	k.f, k.q, k.model = fm, qm, nil
+	return nil
+}
+
+// compute the state transition and process noise covariance from the timestep of each prediction
+//
+// use it when predictions are not evenly spaced, the shapes are checked on every Predict
+//
+// time: O(1)
+func (k *Kalman[T]) TransitionFunc(model func(dt T) (f, q [][]T)) {
+	k.model = model
+}
+
+// use the control input matrix b, n by m, in every prediction
+//
+// a nil b removes the control input
+//
+// return ErrDimension if b does not have n rows
+//
+// time: O(n*m)
+func (k *Kalman[T]) ControlInput(b [][]T) error {
+	if b == nil {
+		k.b = linalg.Matrix{}
+		return nil
+	}
+
+	bm, ok := linalg.FromRows(b)
+	if !ok || bm.Rows != k.n {
+		return ErrDimension
+	}
+	k.b = bm
+	return nil
+}
+
+// use the measurement matrix h, m by n, and noise covariance r, m by m, in Update
+//
+// return ErrDimension if the shapes do not match
+//
+// time: O(m*n)
+func (k *Kalman[T]) Measurement(h, r [][]T) error {
+	hm, rm, ok := k.measurement(h, r)
+	if !ok {
+		return ErrDimension
+	}
+	k.h, k.r = hm, rm
+	return nil
+}
+
+// propagate the estimate over a timestep dt with control input u
+//
+// u may be nil when there is no control input, dt is only used by a TransitionFunc
+//
+// return ErrDimension if u or the model matrices do not match the filter, leaving it unchanged
+//
+// time: O(n^3)
+func (k *Kalman[T]) Predict(u []T, dt T) error {
+	f, q := k.f, k.q
+	if k.model != nil {
+		var ok bool
+		if f, q, ok = k.square(k.model(dt)); !ok {
+			return ErrDimension
+		}
+	}
+
+	x := make([]float64, k.n)
+	f.MulVec(k.x, x)
+
+	if u != nil {
+		if k.b.Rows == 0 || len(u) != k.b.Cols {
+			return ErrDimension
+		}
+		bu := make([]float64, k.n)
+		k.b.MulVec(toFloats(u), bu)
+		for i := range x {
+			x[i] += bu[i]
+		}
+	}
+
+	k.x = x
+	k.p = symmetric(f.Mul(k.p).Mul(f.T()).Add(q))
+	return nil
+}
+
+// correct the estimate with measurement z using the model set by Measurement
+//
+// return ErrDimension if no measurement model is set or z does not match it,
+// and ErrSingular if the innovation covariance cannot be inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3)
+func (k *Kalman[T]) Update(z []T) error {
+	if k.h.Rows == 0 {
+		return ErrDimension
+	}
+	return k.update(z, k.h, k.r)
+}
+
+// correct the estimate with measurement z from a sensor with its own model h and r
+//
+// return ErrDimension if the shapes do not match and ErrSingular if the innovation covariance
+// cannot be inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3)
+func (k *Kalman[T]) UpdateWith(z []T, h, r [][]T) error {
+	hm, rm, ok := k.measurement(h, r)
+	if !ok {
+		return ErrDimension
+	}
+	return k.update(z, hm, rm)
+}
+
+func (k *Kalman[T]) update(z []T, h, r linalg.Matrix) error {
+	m := h.Rows
+	if len(z) != m {
+		return ErrDimension
+	}
+
+	// innovation y = z - H*x
+	hx := make([]float64, m)
+	h.MulVec(k.x, hx)
+	y := make([]float64, m)
+	for i := range y {
+		y[i] = float64(z[i]) - hx[i]
+	}
+
+	// S = H*P*H^t + R
+	pht := k.p.Mul(h.T())
+	s := symmetric(h.Mul(pht).Add(r))
+
+	return k.correct(y, s, pht, func(gain linalg.Matrix) linalg.Matrix {
+		// joseph form
+		ikh := linalg.Identity(k.n).Sub(gain.Mul(h))
+		return ikh.Mul(k.p).Mul(ikh.T()).Add(gain.Mul(r).Mul(gain.T()))
+	})
+}
+
+// convert h and r to m by n and m by m matrices
+func (k *Kalman[T]) measurement(h, r [][]T) (hm, rm linalg.Matrix, ok bool) {
+	hm, okh := linalg.FromRows(h)
+	rm, okr := linalg.FromRows(r)
+	if !okh || !okr || hm.Cols != k.n || rm.Rows != hm.Rows || rm.Cols != hm.Rows {
+		return hm, rm, false
+	}
+	return hm, rm, true
+}
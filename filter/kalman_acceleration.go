@@ -0,0 +1,134 @@
+package filter
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// estimate position, velocity and acceleration from scalar position measurements
+//
+// use a constant acceleration model
+//
+// state x = [pos, vel, acc]^t
+//
+// model
+//
+//	x_k = F*x_{k-1} + w
+//	z_k = H*x_k + v
+//
+// with
+//
+//	F = [1 dt dt^2/2; 0 1 dt; 0 0 1]
+//	H = [1 0 0]
+//
+// q is the process variance of jerk noise
+// r is the measurement variance of position noise
+//
+// velocity measurements, for example from an encoder, can be fused with ComputeVel
+//
+// this type is not safe for concurrent use
+type KalmanConstAcc[T c.Float] struct {
+	axis *kinematicAxis
+}
+
+// create a constant acceleration kalman filter
+//
+// q is the jerk noise variance
+//
+// r is the position measurement noise variance
+//
+// p0, v0 and a0 are the initial position, velocity and acceleration covariances
+//
+// return ErrNoise if q is negative or r is not positive
+func NewKalmanConstAcc[T c.Float](
+	q, r float64,
+	initialPos T,
+	initialVel T,
+	initialAcc T,
+	p0 float64,
+	v0 float64,
+	a0 float64,
+) (*KalmanConstAcc[T], error) {
+	axis, err := newKinematicAxis(3, q, r, float64(initialPos), float64(initialVel), float64(initialAcc), p0, v0, a0)
+	if err != nil {
+		return nil, err
+	}
+	return &KalmanConstAcc[T]{axis: axis}, nil
+}
+
+// set the variance of velocity measurements used by ComputeVel, equal to r by default
+//
+// return ErrNoise if rv is not positive, leaving the variance unchanged
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) VelocityNoise(rv float64) error {
+	return k.axis.velocityNoise(rv)
+}
+
+// reset the filter state
+func (k *KalmanConstAcc[T]) Reset(initialPos, initialVel, initialAcc T, p0, v0, a0 float64) {
+	k.axis.reset(float64(initialPos), float64(initialVel), float64(initialAcc), p0, v0, a0)
+}
+
+// propagate the estimate over dt without a measurement
+//
+// does nothing if dt is not positive
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Predict(dt T) {
+	k.axis.predict(float64(dt))
+}
+
+// compute the next estimate from a position measurement and dt
+//
+// a non positive dt fuses z at the current time, without prediction
+//
+// return the updated position estimate, a z that is not finite keeps the prediction
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Compute(z T, dt T) T {
+	k.axis.predict(float64(dt))
+	_ = k.axis.update(float64(z))
+	return k.Pos()
+}
+
+// compute the next estimate from a position measurement z, a velocity measurement v and dt
+//
+// a non positive dt fuses the measurements at the current time, without prediction
+//
+// return the updated position estimate, measurements that are not finite keep the prediction
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) ComputeVel(z, v T, dt T) T {
+	k.axis.predict(float64(dt))
+	_ = k.axis.updateVel(float64(z), float64(v))
+	return k.Pos()
+}
+
+// return the current position estimate
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Pos() T {
+	return T(k.axis.at(0))
+}
+
+// return the current velocity estimate
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Vel() T {
+	return T(k.axis.at(1))
+}
+
+// return the current acceleration estimate
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Acc() T {
+	return T(k.axis.at(2))
+}
+
+// return a copy of the 3x3 estimate covariance
+//
+// time: O(1)
+func (k *KalmanConstAcc[T]) Covariance() [][]T {
+	return linalg.ToRows[T](k.axis.k.p)
+}
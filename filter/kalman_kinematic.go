@@ -0,0 +1,322 @@
+package filter
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/geometry"
+)
+
+// select the motion model of a kinematic kalman filter
+type Kinematics int
+
+const (
+	// state [pos, vel] per axis, driven by white acceleration noise
+	ConstantVelocity Kinematics = iota
+
+	// state [pos, vel, acc] per axis, driven by white jerk noise
+	ConstantAcceleration
+)
+
+// track position, velocity and optionally acceleration along one axis
+//
+// built on the general kalman filter with a fixed position measurement and a second one for
+// combined position and velocity measurements
+type kinematicAxis struct {
+	k     *Kalman[float64]
+	order int
+	r, rv float64
+}
+
+// create an axis of the given order, 2 or 3, with the initial state and its variances
+//
+// return ErrNoise if q is negative or r is not positive
+func newKinematicAxis(order int, q, r float64, pos, vel, acc, p0, v0, a0 float64) (*kinematicAxis, error) {
+	if !(q >= 0) || !(r > 0) {
+		return nil, ErrNoise
+	}
+
+	x0 := []float64{pos, vel, acc}[:order]
+	k, err := NewKalman(x0, kinematicCovariance(order, p0, v0, a0))
+	if err != nil {
+		return nil, err
+	}
+	k.TransitionFunc(func(dt float64) (f, qd [][]float64) {
+		return kinematicModel(order, q, dt)
+	})
+
+	h := make([]float64, order)
+	h[0] = 1
+	if err := k.Measurement([][]float64{h}, [][]float64{{r}}); err != nil {
+		return nil, err
+	}
+
+	return &kinematicAxis{k: k, order: order, r: r, rv: r}, nil
+}
+
+// set the velocity measurement variance
+//
+// return ErrNoise if rv is not positive
+func (a *kinematicAxis) velocityNoise(rv float64) error {
+	if !(rv > 0) {
+		return ErrNoise
+	}
+	a.rv = rv
+	return nil
+}
+
+func (a *kinematicAxis) reset(pos, vel, acc, p0, v0, a0 float64) {
+	a.k.Reset([]float64{pos, vel, acc}[:a.order], kinematicCovariance(a.order, p0, v0, a0))
+}
+
+// predict over dt when it is positive
+func (a *kinematicAxis) predict(dt float64) {
+	if dt > 0 {
+		a.k.Predict(nil, dt)
+	}
+}
+
+// fuse a position measurement
+//
+// r is positive, so the innovation covariance is always invertible and the update only fails
+// with ErrSingular for a z that is not finite, which leaves the prediction in place
+func (a *kinematicAxis) update(z float64) error {
+	return a.k.Update([]float64{z})
+}
+
+// fuse a position and a velocity measurement
+//
+// r and rv are positive, so as with update it only fails for measurements that are not finite
+func (a *kinematicAxis) updateVel(z, v float64) error {
+	h := [][]float64{make([]float64, a.order), make([]float64, a.order)}
+	h[0][0], h[1][1] = 1, 1
+	return a.k.UpdateWith([]float64{z, v}, h, [][]float64{{a.r, 0}, {0, a.rv}})
+}
+
+// return state component i, zero if the axis does not track it
+func (a *kinematicAxis) at(i int) float64 {
+	if i >= a.order {
+		return 0
+	}
+	return a.k.x[i]
+}
+
+// return the initial covariance diag(p0, v0, a0) truncated to the order
+func kinematicCovariance(order int, p0, v0, a0 float64) [][]float64 {
+	d := []float64{p0, v0, a0}
+	p := make([][]float64, order)
+	for i := range p {
+		p[i] = make([]float64, order)
+		p[i][i] = d[i]
+	}
+	return p
+}
+
+// return the transition and process noise of a kinematic chain of the given order over dt
+//
+// the highest derivative is driven by piecewise constant white noise of variance q
+//
+//	G = [dt^(order-1)/(order-1)!, ..., dt]^t,  Q = q*G*G^t
+func kinematicModel(order int, q, dt float64) (f, qd [][]float64) {
+	g := []float64{dt * dt / 2, dt}
+	if order == 3 {
+		g = []float64{dt * dt * dt / 6, dt * dt / 2, dt}
+	}
+
+	f = make([][]float64, order)
+	qd = make([][]float64, order)
+	for i := range order {
+		f[i] = make([]float64, order)
+		qd[i] = make([]float64, order)
+
+		// taylor expansion of the state over dt
+		term := 1.0
+		for j := i; j < order; j++ {
+			f[i][j] = term
+			term *= dt / float64(j-i+1)
+		}
+		for j := range order {
+			qd[i][j] = q * g[i] * g[j]
+		}
+	}
+	return f, qd
+}
+
+// ====================
+// multi axis kinematic
+// ====================
+
+// estimate position, velocity and optionally acceleration of a point in 2d or 3d
+//
+// each axis runs an independent kinematic kalman filter with the same noise parameters,
+// measurements are geometry vectors and z is ignored in 2d
+//
+// the covariance is block diagonal, one block per axis in the order x, y, z, and the state
+// of each block is [pos, vel] or [pos, vel, acc]
+//
+// q is the variance of the acceleration noise, or of the jerk noise for ConstantAcceleration
+//
+// r is the position measurement variance
+//
+// this type is not safe for concurrent use
+type KalmanKinematic[T c.Float] struct {
+	axes []*kinematicAxis
+}
+
+// create a kinematic kalman filter with dims axes, 2 or 3
+//
+// p0, v0 and a0 are the initial position, velocity and acceleration variances of each axis,
+// a0 is ignored for ConstantVelocity
+//
+// return ErrDimension if dims is not 2 or 3 and ErrNoise if q is negative or r is not positive
+func NewKalmanKinematic[T c.Float](
+	dims int,
+	model Kinematics,
+	q, r float64,
+	initialPos geometry.Vector[T],
+	p0, v0, a0 float64,
+) (*KalmanKinematic[T], error) {
+	if dims != 2 && dims != 3 {
+		return nil, ErrDimension
+	}
+
+	order := 2
+	if model == ConstantAcceleration {
+		order = 3
+	}
+
+	pos := vectorComponents(initialPos)
+	k := &KalmanKinematic[T]{axes: make([]*kinematicAxis, dims)}
+	for i := range k.axes {
+		a, err := newKinematicAxis(order, q, r, pos[i], 0, 0, p0, v0, a0)
+		if err != nil {
+			return nil, err
+		}
+		k.axes[i] = a
+	}
+	return k, nil
+}
+
+// set the variance of velocity measurements used by ComputeVel, equal to r by default
+//
+// return ErrNoise if rv is not positive, leaving the variance unchanged
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) VelocityNoise(rv float64) error {
+	if !(rv > 0) {
+		return ErrNoise
+	}
+	for _, a := range k.axes {
+		a.velocityNoise(rv)
+	}
+	return nil
+}
+
+// reset the filter to a position at rest with the given variances
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Reset(initialPos geometry.Vector[T], p0, v0, a0 float64) {
+	pos := vectorComponents(initialPos)
+	for i, a := range k.axes {
+		a.reset(pos[i], 0, 0, p0, v0, a0)
+	}
+}
+
+// propagate the estimate over dt without a measurement
+//
+// does nothing if dt is not positive
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Predict(dt T) {
+	for _, a := range k.axes {
+		a.predict(float64(dt))
+	}
+}
+
+// predict over dt and fuse a position measurement z
+//
+// a non positive dt fuses z at the current time, without prediction
+//
+// return the updated position estimate, an axis whose component of z is not finite keeps
+// its prediction
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Compute(z geometry.Vector[T], dt T) geometry.Vector[T] {
+	zs := vectorComponents(z)
+	for i, a := range k.axes {
+		a.predict(float64(dt))
+		_ = a.update(zs[i])
+	}
+	return k.Pos()
+}
+
+// predict over dt and fuse a position measurement z and a velocity measurement v
+//
+// a non positive dt fuses the measurements at the current time, without prediction
+//
+// return the updated position estimate, an axis whose components of z or v are not finite
+// keeps its prediction
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) ComputeVel(z, v geometry.Vector[T], dt T) geometry.Vector[T] {
+	zs, vs := vectorComponents(z), vectorComponents(v)
+	for i, a := range k.axes {
+		a.predict(float64(dt))
+		_ = a.updateVel(zs[i], vs[i])
+	}
+	return k.Pos()
+}
+
+// return the current position estimate
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Pos() geometry.Vector[T] {
+	return k.component(0)
+}
+
+// return the current velocity estimate
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Vel() geometry.Vector[T] {
+	return k.component(1)
+}
+
+// return the current acceleration estimate, zero for ConstantVelocity
+//
+// time: O(1)
+func (k *KalmanKinematic[T]) Acc() geometry.Vector[T] {
+	return k.component(2)
+}
+
+// return a copy of the block diagonal estimate covariance
+//
+// time: O(n^2) where n is the state size
+func (k *KalmanKinematic[T]) Covariance() [][]T {
+	order := k.axes[0].order
+	n := len(k.axes) * order
+
+	out := make([][]T, n)
+	for i := range out {
+		out[i] = make([]T, n)
+	}
+	for b, a := range k.axes {
+		p := a.k.p
+		for i := range order {
+			for j := range order {
+				out[b*order+i][b*order+j] = T(p.At(i, j))
+			}
+		}
+	}
+	return out
+}
+
+func (k *KalmanKinematic[T]) component(i int) geometry.Vector[T] {
+	v := make([]T, len(k.axes))
+	for j, a := range k.axes {
+		v[j] = T(a.at(i))
+	}
+	return geometry.NewVector(v...)
+}
+
+// return the x, y and z components of a vector as float64
+func vectorComponents[T c.Float](v geometry.Vector[T]) [3]float64 {
+	return [3]float64{float64(v.X), float64(v.Y), float64(v.Z)}
+}
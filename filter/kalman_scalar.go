@@ -13,6 +13,11 @@ type KalmanScalar[T c.Float] struct {
 
 	xHat float64
 	p    float64
+
+	// innovation gate
+	gate     float64
+	nis      float64
+	rejected bool
 }
 
 // create a 1d kalman filter
@@ -33,14 +38,45 @@ func NewKalmanScalar[T c.Float](processVariance, measurementVariance, initialErr
 	}
 }
 
+// skip the update for measurements whose normalised innovation squared exceeds threshold
+//
+// the nis of a consistent filter is chi square distributed with one degree of freedom, so
+// 6.63 rejects 1% of good measurements and 10.83 rejects 0.1%
+//
+// the variance keeps growing while measurements are rejected, so a persistent change is
+// eventually accepted
+//
+// a threshold that is not positive disables the gate
+func (k *KalmanScalar[T]) WithGate(threshold T) *KalmanScalar[T] {
+	k.gate = float64(threshold)
+	return k
+}
+
 // reset the filter state
 func (k *KalmanScalar[T]) Reset(initialErrorCovariance float64, initialEstimate T) {
 	k.p = initialErrorCovariance
 	k.xHat = float64(initialEstimate)
+	k.nis, k.rejected = 0, false
+}
+
+// return the normalised innovation squared of the last measurement
+//
+// time: O(1)
+func (k *KalmanScalar[T]) Nis() T {
+	return T(k.nis)
+}
+
+// report whether the gate rejected the last measurement
+//
+// time: O(1)
+func (k *KalmanScalar[T]) Rejected() bool {
+	return k.rejected
 }
 
 // compute the next estimate from a measurement
 //
+// a measurement rejected by the gate only advances the prediction
+//
 // time: O(1)
 func (k *KalmanScalar[T]) Compute(measurement T) T {
 	// predict
@@ -53,8 +89,16 @@ func (k *KalmanScalar[T]) Compute(measurement T) T {
 		return T(k.xHat)
 	}
 
+	innovation := float64(measurement) - xHatPred
+	k.nis = innovation * innovation / den
+	k.rejected = k.gate > 0 && k.nis > k.gate
+	if k.rejected {
+		k.p = pPred
+		return T(k.xHat)
+	}
+
 	kgain := pPred / den
-	k.xHat = xHatPred + kgain*(float64(measurement)-xHatPred)
+	k.xHat = xHatPred + kgain*innovation
 	k.p = (1 - kgain) * pPred
 
 	return T(k.xHat)
@@ -25,10 +25,13 @@ import (
 // q is the process variance of acceleration noise
 // r is the measurement variance of position noise
 //
+// velocity measurements, for example from an encoder, can be fused with ComputeVel
+//
 // this type is not safe for concurrent use
 type KalmanConstVel[T c.Float] struct {
-	q float64
-	r float64
+	q  float64
+	r  float64
+	rv float64
 
 	// state estimate
 	x0 float64 // pos
@@ -57,8 +60,9 @@ func NewKalmanConstVel[T c.Float](
 	v0 float64,
 ) *KalmanConstVel[T] {
 	return &KalmanConstVel[T]{
-		q: q,
-		r: r,
+		q:  q,
+		r:  r,
+		rv: r,
 
 		x0: float64(initialPos),
 		x1: float64(initialVel),
@@ -69,6 +73,19 @@ func NewKalmanConstVel[T c.Float](
 	}
 }
 
+// set the variance of velocity measurements used by ComputeVel, equal to r by default
+//
+// return ErrNoise if rv is not positive, leaving the variance unchanged
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) VelocityNoise(rv float64) error {
+	if !(rv > 0) {
+		return ErrNoise
+	}
+	k.rv = rv
+	return nil
+}
+
 // reset the filter state
 func (k *KalmanConstVel[T]) Reset(initialPos, initialVel T, p0, v0 float64) {
 	k.x0 = float64(initialPos)
@@ -92,29 +109,7 @@ func (k *KalmanConstVel[T]) Compute(z T, dt T) T {
 		return 0
 	}
 
-	// ----------
-	// predict
-	// ----------
-	// x = F*x
-	x0p := k.x0 + dtf*k.x1
-	x1p := k.x1
-
-	// process noise for constant velocity with acceleration noise
-	// qd = q * [dt^4/4  dt^3/2
-	//          dt^3/2  dt^2]
-	dt2 := dtf * dtf
-	dt3 := dt2 * dtf
-	dt4 := dt2 * dt2
-
-	q00 := k.q * (dt4 / 4)
-	q01 := k.q * (dt3 / 2)
-	q11 := k.q * dt2
-
-	// P = F*P*F^T + Q
-	// with P symmetric (p10 = p01)
-	p00p := k.p00 + 2*dtf*k.p01 + dt2*k.p11 + q00
-	p01p := k.p01 + dtf*k.p11 + q01
-	p11p := k.p11 + q11
+	x0p, x1p, p00p, p01p, p11p := k.predict(dtf)
 
 	// ----------
 	// update
@@ -154,6 +149,86 @@ func (k *KalmanConstVel[T]) Compute(z T, dt T) T {
 	return T(k.x0)
 }
 
+// compute the next estimate from a position measurement z, a velocity measurement v and dt
+//
+// return the updated position estimate
+//
+// return zero if dt is not positive
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) ComputeVel(z, v T, dt T) T {
+	dtf := float64(dt)
+	if dtf <= 0 {
+		return 0
+	}
+
+	x0p, x1p, p00p, p01p, p11p := k.predict(dtf)
+
+	// ----------
+	// update
+	// ----------
+	// with H = I the innovation is y = [z - pos, v - vel]
+	innov0 := float64(z) - x0p
+	innov1 := float64(v) - x1p
+
+	// S = P + R with R = diag(r, rv)
+	s00 := p00p + k.r
+	s11 := p11p + k.rv
+	det := s00*s11 - p01p*p01p
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		// keep predicted state if update is ill conditioned
+		k.x0 = x0p
+		k.x1 = x1p
+		k.p00 = p00p
+		k.p01 = p01p
+		k.p11 = p11p
+		return T(k.x0)
+	}
+
+	// K = P*S^-1
+	k00 := (p00p*s11 - p01p*p01p) / det
+	k01 := p01p * k.r / det
+	k10 := p01p * k.rv / det
+	k11 := (p11p*s00 - p01p*p01p) / det
+
+	// x = x + K*y
+	k.x0 = x0p + k00*innov0 + k01*innov1
+	k.x1 = x1p + k10*innov0 + k11*innov1
+
+	// P = (I - K) P
+	k.p00 = p00p - k00*p00p - k01*p01p
+	k.p01 = p01p - k00*p01p - k01*p11p
+	k.p11 = p11p - k10*p01p - k11*p11p
+
+	return T(k.x0)
+}
+
+// return the predicted state and covariance over dt
+func (k *KalmanConstVel[T]) predict(dtf float64) (x0p, x1p, p00p, p01p, p11p float64) {
+	// x = F*x
+	x0p = k.x0 + dtf*k.x1
+	x1p = k.x1
+
+	// process noise for constant velocity with acceleration noise
+	// qd = q * [dt^4/4  dt^3/2
+	//          dt^3/2  dt^2]
+	dt2 := dtf * dtf
+	dt3 := dt2 * dtf
+	dt4 := dt2 * dt2
+
+	q00 := k.q * (dt4 / 4)
+	q01 := k.q * (dt3 / 2)
+	q11 := k.q * dt2
+
+	// P = F*P*F^T + Q
+	// with P symmetric (p10 = p01)
+	p00p = k.p00 + 2*dtf*k.p01 + dt2*k.p11 + q00
+	p01p = k.p01 + dtf*k.p11 + q01
+	p11p = k.p11 + q11
+
+	return x0p, x1p, p00p, p01p, p11p
+}
+
 // return the current position estimate
 //
 // time: O(1)
@@ -167,3 +242,13 @@ func (k *KalmanConstVel[T]) Pos() T {
 func (k *KalmanConstVel[T]) Vel() T {
 	return T(k.x1)
 }
+
+// return a copy of the 2x2 estimate covariance
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) Covariance() [][]T {
+	return [][]T{
+		{T(k.p00), T(k.p01)},
+		{T(k.p01), T(k.p11)},
+	}
+}
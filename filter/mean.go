@@ -4,11 +4,14 @@ import c "github.com/vistormu/go-dsa/constraints"
 
 // compute a sliding window mean
 //
+// the samples are kept in a ring buffer and the running sum is compensated, so the mean does
+// not drift over long runs
+//
 // this type is not safe for concurrent use
 type Mean[T c.Float] struct {
 	window int
-	values []T
-	sum    T
+	values ring[T]
+	sum    compensated
 }
 
 // create a mean filter with a fixed window size
@@ -21,14 +24,14 @@ func NewMean[T c.Float](windowSize int) *Mean[T] {
 
 	return &Mean[T]{
 		window: windowSize,
-		values: make([]T, 0, windowSize),
+		values: newRing[T](windowSize),
 	}
 }
 
 // reset the stored samples
 func (m *Mean[T]) Reset() {
-	m.values = m.values[:0]
-	m.sum = 0
+	m.values.reset()
+	m.sum = compensated{}
 }
 
 // compute the mean of the current window after inserting value
@@ -39,15 +42,9 @@ func (m *Mean[T]) Compute(value T) T {
 		return 0
 	}
 
-	if len(m.values) == m.window {
-		oldest := m.values[0]
-		copy(m.values, m.values[1:])
-		m.values[m.window-1] = value
-		m.sum = m.sum - oldest + value
-		return m.sum / T(m.window)
+	if _, old, evicted := m.values.push(value); evicted {
+		m.sum.add(-float64(old))
 	}
-
-	m.values = append(m.values, value)
-	m.sum += value
-	return m.sum / T(len(m.values))
+	m.sum.add(float64(value))
+	return T(m.sum.value() / float64(m.values.n))
 }
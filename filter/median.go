@@ -1,17 +1,18 @@
 package filter
 
 import (
-	"slices"
-
 	c "github.com/vistormu/go-dsa/constraints"
 )
 
 // compute a sliding window median
 //
+// the window is kept split between two heaps, so each sample costs O(log w) and
+// no memory is allocated after construction
+//
 // this type is not safe for concurrent use
 type Median[T c.Number] struct {
 	window int
-	values []T
+	rank   rankWindow[T]
 }
 
 // create a median filter with a fixed window size
@@ -24,37 +25,29 @@ func NewMedian[T c.Number](windowSize int) *Median[T] {
 
 	return &Median[T]{
 		window: windowSize,
-		values: make([]T, 0, windowSize),
+		rank:   newRankWindow[T](windowSize, 0.5),
 	}
 }
 
 // reset the stored samples
 func (m *Median[T]) Reset() {
-	m.values = m.values[:0]
+	m.rank.reset()
 }
 
 // compute the median of the current window after inserting value
 //
-// time: O(w log w) where w is the window size
+// time: O(log w) where w is the window size
 func (m *Median[T]) Compute(value T) T {
 	if m.window <= 0 {
 		return 0
 	}
 
-	m.values = append(m.values, value)
-	if len(m.values) > m.window {
-		copy(m.values, m.values[1:])
-		m.values = m.values[:m.window]
-	}
+	r := &m.rank
+	r.push(value)
 
-	tmp := make([]T, len(m.values))
-	copy(tmp, m.values)
-	slices.Sort(tmp)
-
-	n := len(tmp)
-	if n%2 == 1 {
-		return tmp[n/2]
+	lower := r.value(r.lo[0])
+	if r.ring.n%2 == 1 {
+		return lower
 	}
-
-	return (tmp[n/2-1] + tmp[n/2]) / 2
+	return (lower + r.value(r.hi[0])) / 2
 }
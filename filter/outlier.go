@@ -0,0 +1,229 @@
+package filter
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ======
+// hampel
+// ======
+
+// replace outliers with the median of a sliding window
+//
+// a sample further than threshold robust standard deviations from the median of the window
+// that ends with it is rejected, the robust standard deviation being the scaled median
+// absolute deviation
+//
+// rejected samples stay in the window, the median and deviation are barely moved by them
+//
+// this type is not safe for concurrent use
+type Hampel[T c.Float] struct {
+	window    int
+	threshold float64
+	mad       madWindow[T]
+}
+
+// create a hampel identifier with a fixed window size and a threshold in standard deviations,
+// usually 3
+//
+// a negative threshold is treated as zero
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewHampel[T c.Float](windowSize int, threshold T) *Hampel[T] {
+	if windowSize <= 0 {
+		return &Hampel[T]{}
+	}
+
+	return &Hampel[T]{
+		window:    windowSize,
+		threshold: max(0, float64(threshold)),
+		mad:       newMadWindow[T](windowSize),
+	}
+}
+
+// reset the stored samples
+func (h *Hampel[T]) Reset() {
+	h.mad.reset()
+}
+
+// insert value and return it, or the median of the window and true if it is an outlier
+//
+// time: O(w) where w is the window size
+func (h *Hampel[T]) Compute(value T) (T, bool) {
+	if h.window <= 0 {
+		return 0, false
+	}
+
+	h.mad.push(value)
+	median := h.mad.median()
+	if math.Abs(float64(value)-median) > h.threshold*h.mad.deviation(median) {
+		return T(median), true
+	}
+	return value, false
+}
+
+// ============
+// z-score gate
+// ============
+
+// reject samples far from the recent history of a signal
+//
+// the z-score of a sample is its distance to the mean of the previous window samples in
+// standard deviations, WithMad switches to the robust z-score with the median and the scaled
+// median absolute deviation, which outliers in the window barely move
+//
+// every sample enters the window, so a persistent change is accepted once it dominates it
+//
+// this type is not safe for concurrent use
+type ZScoreGate[T c.Float] struct {
+	window    int
+	threshold float64
+	robust    bool
+
+	moments moments
+	mad     madWindow[T]
+}
+
+// create a z-score gate with a fixed window size and a threshold in standard deviations
+//
+// a negative threshold is treated as zero
+//
+// if windowSize is less than or equal to zero, it creates an empty gate that returns zero
+func NewZScoreGate[T c.Float](windowSize int, threshold T) *ZScoreGate[T] {
+	if windowSize <= 0 {
+		return &ZScoreGate[T]{}
+	}
+
+	return &ZScoreGate[T]{
+		window:    windowSize,
+		threshold: max(0, float64(threshold)),
+		moments:   newMoments(windowSize),
+		mad:       newMadWindow[T](windowSize),
+	}
+}
+
+// use the median and median absolute deviation instead of the mean and standard deviation
+//
+// it resets the stored samples
+func (g *ZScoreGate[T]) WithMad(robust bool) *ZScoreGate[T] {
+	g.robust = robust
+	g.Reset()
+	return g
+}
+
+// reset the stored samples
+func (g *ZScoreGate[T]) Reset() {
+	g.moments.reset()
+	g.mad.reset()
+}
+
+// insert value and return it, or the centre of the previous samples and true if it is
+// an outlier
+//
+// samples are accepted until the window holds two of them
+//
+// time: O(1), O(w) with WithMad where w is the window size
+func (g *ZScoreGate[T]) Compute(value T) (T, bool) {
+	if g.window <= 0 {
+		return 0, false
+	}
+
+	var n int
+	var center, scale float64
+	if g.robust {
+		n = g.mad.rank.ring.n
+		center = g.mad.median()
+		scale = g.mad.deviation(center)
+		g.mad.push(value)
+	} else {
+		n = g.moments.ring.n
+		center = g.moments.mean()
+		scale = math.Sqrt(g.moments.variance(true))
+		g.moments.push(float64(value))
+	}
+
+	if n >= 2 && math.Abs(float64(value)-center) > g.threshold*scale {
+		return T(center), true
+	}
+	return value, false
+}
+
+// ================
+// spike suppressor
+// ================
+
+// hold a signal through short spikes
+//
+// a sample that moves away from the last accepted one faster than rate is a spike, and the
+// output holds the last accepted value, or WithSlew follows the sample at the maximum rate
+//
+// a jump that lasts more than maxSpike samples is a real change and is accepted
+//
+// this type is not safe for concurrent use
+type SpikeSuppressor[T c.Float] struct {
+	rate     T
+	maxSpike int
+	slew     bool
+
+	accepted T
+	elapsed  T
+	spike    int
+	y        T
+	init     bool
+}
+
+// create a spike suppressor with a maximum absolute rate per second and the number of
+// samples of the longest spike to suppress
+//
+// rate must be non negative and maxSpike at least one
+func NewSpikeSuppressor[T c.Float](rate T, maxSpike int) *SpikeSuppressor[T] {
+	return &SpikeSuppressor[T]{rate: max(0, rate), maxSpike: max(1, maxSpike)}
+}
+
+// follow spikes at the maximum rate instead of holding the last accepted value
+func (s *SpikeSuppressor[T]) WithSlew(slew bool) *SpikeSuppressor[T] {
+	s.slew = slew
+	return s
+}
+
+// reset internal state
+func (s *SpikeSuppressor[T]) Reset() {
+	s.accepted, s.elapsed, s.y = 0, 0, 0
+	s.spike = 0
+	s.init = false
+}
+
+// compute the suppressed value given input x and timestep dt, and whether x was rejected
+//
+// return zero if dt is not positive
+//
+// time: O(1)
+func (s *SpikeSuppressor[T]) Compute(x, dt T) (T, bool) {
+	if dt <= 0 {
+		return 0, false
+	}
+
+	if !s.init {
+		s.accepted, s.y = x, x
+		s.init = true
+		return x, false
+	}
+
+	s.elapsed += dt
+	if max(x-s.accepted, s.accepted-x) <= s.rate*s.elapsed || s.spike >= s.maxSpike {
+		s.accepted, s.y = x, x
+		s.elapsed, s.spike = 0, 0
+		return x, false
+	}
+
+	s.spike++
+	if s.slew {
+		maxDelta := s.rate * dt
+		s.y += min(maxDelta, max(-maxDelta, x-s.y))
+	} else {
+		s.y = s.accepted
+	}
+	return s.y, true
+}
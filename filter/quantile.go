@@ -0,0 +1,146 @@
+package filter
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ========
+// quantile
+// ========
+
+// compute a sliding window quantile
+//
+// the quantile interpolates linearly between the two nearest samples, so q = 0.5 is the
+// median, q = 0 the minimum and q = 1 the maximum of the window
+//
+// the window is kept split between two heaps at the quantile rank, so each sample costs
+// O(log w) and no memory is allocated after construction
+//
+// this type is not safe for concurrent use
+type Quantile[T c.Number] struct {
+	window int
+	rank   rankWindow[T]
+}
+
+// create a quantile filter with a fixed window size and quantile q, clamped to [0, 1]
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewQuantile[T c.Number](windowSize int, q float64) *Quantile[T] {
+	if windowSize <= 0 {
+		return &Quantile[T]{}
+	}
+
+	return &Quantile[T]{
+		window: windowSize,
+		rank:   newRankWindow[T](windowSize, q),
+	}
+}
+
+// create a percentile filter with a fixed window size and percentile p in [0, 100]
+func NewPercentile[T c.Number](windowSize int, p float64) *Quantile[T] {
+	return NewQuantile[T](windowSize, p/100)
+}
+
+// return the quantile in [0, 1]
+//
+// time: O(1)
+func (f *Quantile[T]) Q() float64 {
+	return f.rank.q
+}
+
+// reset the stored samples
+func (f *Quantile[T]) Reset() {
+	f.rank.reset()
+}
+
+// compute the quantile of the current window after inserting value
+//
+// time: O(log w) where w is the window size
+func (f *Quantile[T]) Compute(value T) T {
+	if f.window <= 0 {
+		return 0
+	}
+
+	f.rank.push(value)
+	return T(f.rank.quantile())
+}
+
+// ===
+// min
+// ===
+
+// compute a sliding window minimum
+//
+// a monotonic deque keeps the candidates, so each sample costs O(1) amortised and
+// no memory is allocated after construction
+//
+// this type is not safe for concurrent use
+type Min[T c.Number] struct {
+	window int
+	deque  monotonicDeque[T]
+}
+
+// create a minimum filter with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewMin[T c.Number](windowSize int) *Min[T] {
+	if windowSize <= 0 {
+		return &Min[T]{}
+	}
+	return &Min[T]{window: windowSize, deque: newMonotonicDeque[T](windowSize, false)}
+}
+
+// reset the stored samples
+func (f *Min[T]) Reset() {
+	f.deque.reset()
+}
+
+// compute the minimum of the current window after inserting value
+//
+// time: O(1) amortised
+func (f *Min[T]) Compute(value T) T {
+	if f.window <= 0 {
+		return 0
+	}
+	return f.deque.push(value)
+}
+
+// ===
+// max
+// ===
+
+// compute a sliding window maximum
+//
+// a monotonic deque keeps the candidates, so each sample costs O(1) amortised and
+// no memory is allocated after construction
+//
+// this type is not safe for concurrent use
+type Max[T c.Number] struct {
+	window int
+	deque  monotonicDeque[T]
+}
+
+// create a maximum filter with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewMax[T c.Number](windowSize int) *Max[T] {
+	if windowSize <= 0 {
+		return &Max[T]{}
+	}
+	return &Max[T]{window: windowSize, deque: newMonotonicDeque[T](windowSize, true)}
+}
+
+// reset the stored samples
+func (f *Max[T]) Reset() {
+	f.deque.reset()
+}
+
+// compute the maximum of the current window after inserting value
+//
+// time: O(1) amortised
+func (f *Max[T]) Compute(value T) T {
+	if f.window <= 0 {
+		return 0
+	}
+	return f.deque.push(value)
+}
@@ -0,0 +1,384 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/vistormu/go-dsa/internal/snapshot"
+)
+
+// returned when a snapshot cannot be decoded or belongs to another type
+var ErrSnapshot = errors.New("filter: invalid snapshot")
+
+// decode a binary snapshot of the given kind into state
+func decodeBinary(data []byte, kind string, state any) error {
+	if snapshot.Decode(data, kind, state) != nil {
+		return ErrSnapshot
+	}
+	return nil
+}
+
+// decode a json snapshot into state
+func decodeJSON(data []byte, state any) error {
+	if err := json.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshot, err)
+	}
+	return nil
+}
+
+// report whether n stored samples fit a window of the given size
+func validWindow(window, n int) bool {
+	return window >= 0 && n <= window
+}
+
+// ========
+// low pass
+// ========
+
+type lowPassState struct {
+	Alpha float64 `json:"alpha"`
+	Y     float64 `json:"y"`
+	Init  bool    `json:"init"`
+}
+
+func (f *LowPass[T]) state() lowPassState {
+	return lowPassState{Alpha: float64(f.alpha), Y: float64(f.y), Init: f.init}
+}
+
+func (f *LowPass[T]) restore(s lowPassState) {
+	*f = LowPass[T]{alpha: T(s.Alpha), y: T(s.Y), init: s.Init}
+}
+
+// encode the smoothing factor and output in a compact binary form
+//
+// time: O(1)
+func (f *LowPass[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("low_pass", f.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid low pass snapshot, leaving the filter unchanged
+//
+// time: O(1)
+func (f *LowPass[T]) UnmarshalBinary(data []byte) error {
+	var s lowPassState
+	if err := decodeBinary(data, "low_pass", &s); err != nil {
+		return err
+	}
+	f.restore(s)
+	return nil
+}
+
+// encode the smoothing factor and output as json
+//
+// time: O(1)
+func (f *LowPass[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not valid json, leaving the filter unchanged
+//
+// time: O(1)
+func (f *LowPass[T]) UnmarshalJSON(data []byte) error {
+	var s lowPassState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	f.restore(s)
+	return nil
+}
+
+// =============
+// kalman scalar
+// =============
+
+type kalmanScalarState struct {
+	Q float64 `json:"q"`
+	R float64 `json:"r"`
+	X float64 `json:"x"`
+	P float64 `json:"p"`
+
+	// innovation gate and the check of the last measurement
+	Gate     float64 `json:"gate"`
+	Nis      float64 `json:"nis"`
+	Rejected bool    `json:"rejected"`
+}
+
+func (k *KalmanScalar[T]) state() kalmanScalarState {
+	return kalmanScalarState{
+		Q: k.q, R: k.r, X: k.xHat, P: k.p,
+		Gate: k.gate, Nis: k.nis, Rejected: k.rejected,
+	}
+}
+
+func (k *KalmanScalar[T]) restore(s kalmanScalarState) {
+	*k = KalmanScalar[T]{
+		q: s.Q, r: s.R, xHat: s.X, p: s.P,
+		gate: s.Gate, nis: s.Nis, rejected: s.Rejected,
+	}
+}
+
+// encode the noise variances, gate, estimate, covariance and last innovation check in a
+// compact binary form
+//
+// time: O(1)
+func (k *KalmanScalar[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("kalman_scalar", k.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid scalar kalman snapshot, leaving the filter unchanged
+//
+// time: O(1)
+func (k *KalmanScalar[T]) UnmarshalBinary(data []byte) error {
+	var s kalmanScalarState
+	if err := decodeBinary(data, "kalman_scalar", &s); err != nil {
+		return err
+	}
+	k.restore(s)
+	return nil
+}
+
+// encode the noise variances, gate, estimate, covariance and last innovation check as json
+//
+// time: O(1)
+func (k *KalmanScalar[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not valid json, leaving the filter unchanged
+//
+// time: O(1)
+func (k *KalmanScalar[T]) UnmarshalJSON(data []byte) error {
+	var s kalmanScalarState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	k.restore(s)
+	return nil
+}
+
+// =========================
+// kalman constant velocity
+// =========================
+
+type kalmanConstVelState struct {
+	Q   float64 `json:"q"`
+	R   float64 `json:"r"`
+	Rv  float64 `json:"rv"`
+	Pos float64 `json:"pos"`
+	Vel float64 `json:"vel"`
+	P00 float64 `json:"p00"`
+	P01 float64 `json:"p01"`
+	P11 float64 `json:"p11"`
+}
+
+func (k *KalmanConstVel[T]) state() kalmanConstVelState {
+	return kalmanConstVelState{
+		Q: k.q, R: k.r, Rv: k.rv,
+		Pos: k.x0, Vel: k.x1,
+		P00: k.p00, P01: k.p01, P11: k.p11,
+	}
+}
+
+func (k *KalmanConstVel[T]) restore(s kalmanConstVelState) {
+	*k = KalmanConstVel[T]{
+		q: s.Q, r: s.R, rv: s.Rv,
+		x0: s.Pos, x1: s.Vel,
+		p00: s.P00, p01: s.P01, p11: s.P11,
+	}
+}
+
+// encode the noise variances, estimate and covariance in a compact binary form
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("kalman_const_vel", k.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid constant velocity kalman snapshot, leaving the filter unchanged
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) UnmarshalBinary(data []byte) error {
+	var s kalmanConstVelState
+	if err := decodeBinary(data, "kalman_const_vel", &s); err != nil {
+		return err
+	}
+	k.restore(s)
+	return nil
+}
+
+// encode the noise variances, estimate and covariance as json
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not valid json, leaving the filter unchanged
+//
+// time: O(1)
+func (k *KalmanConstVel[T]) UnmarshalJSON(data []byte) error {
+	var s kalmanConstVelState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	k.restore(s)
+	return nil
+}
+
+// ====
+// mean
+// ====
+
+type meanState struct {
+	Window int       `json:"window"`
+	Values []float64 `json:"values"`
+
+	// compensated running sum, kept so a restored filter resumes with the same rounding
+	Sum  float64 `json:"sum"`
+	Comp float64 `json:"comp"`
+}
+
+func (m *Mean[T]) state() meanState {
+	values := make([]float64, m.values.n)
+	for i := range values {
+		values[i] = float64(m.values.at(i))
+	}
+	return meanState{Window: m.window, Values: values, Sum: m.sum.sum, Comp: m.sum.comp}
+}
+
+func (m *Mean[T]) restore(s meanState) error {
+	if !validWindow(s.Window, len(s.Values)) {
+		return ErrSnapshot
+	}
+
+	restored := NewMean[T](s.Window)
+	for _, v := range s.Values {
+		restored.values.push(T(v))
+	}
+	restored.sum = compensated{sum: s.Sum, comp: s.Comp}
+	*m = *restored
+	return nil
+}
+
+// encode the window size, stored samples and running sum in a compact binary form
+//
+// time: O(w) where w is the window size
+func (m *Mean[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("mean", m.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid mean snapshot, leaving the filter unchanged
+//
+// time: O(w) where w is the window size
+func (m *Mean[T]) UnmarshalBinary(data []byte) error {
+	var s meanState
+	if err := decodeBinary(data, "mean", &s); err != nil {
+		return err
+	}
+	return m.restore(s)
+}
+
+// encode the window size, stored samples and running sum as json
+//
+// time: O(w) where w is the window size
+func (m *Mean[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not a valid mean snapshot, leaving the filter unchanged
+//
+// time: O(w) where w is the window size
+func (m *Mean[T]) UnmarshalJSON(data []byte) error {
+	var s meanState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	return m.restore(s)
+}
+
+// ======
+// median
+// ======
+
+type medianState struct {
+	Window int       `json:"window"`
+	Values []float64 `json:"values"`
+}
+
+func (m *Median[T]) state() medianState {
+	values := make([]float64, m.rank.ring.n)
+	for i := range values {
+		values[i] = float64(m.rank.ring.at(i))
+	}
+	return medianState{Window: m.window, Values: values}
+}
+
+func (m *Median[T]) restore(s medianState) error {
+	if !validWindow(s.Window, len(s.Values)) {
+		return ErrSnapshot
+	}
+
+	restored := NewMedian[T](s.Window)
+	for _, v := range s.Values {
+		restored.rank.push(T(v))
+	}
+	*m = *restored
+	return nil
+}
+
+// encode the window size and stored samples in a compact binary form
+//
+// time: O(w) where w is the window size
+func (m *Median[T]) MarshalBinary() ([]byte, error) {
+	return snapshot.Encode("median", m.state()), nil
+}
+
+// restore a snapshot produced by MarshalBinary
+//
+// return ErrSnapshot if data is not a valid median snapshot, leaving the filter unchanged
+//
+// time: O(w log w) where w is the window size
+func (m *Median[T]) UnmarshalBinary(data []byte) error {
+	var s medianState
+	if err := decodeBinary(data, "median", &s); err != nil {
+		return err
+	}
+	return m.restore(s)
+}
+
+// encode the window size and stored samples as json
+//
+// time: O(w) where w is the window size
+func (m *Median[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.state())
+}
+
+// restore a snapshot produced by MarshalJSON
+//
+// return ErrSnapshot if data is not a valid median snapshot, leaving the filter unchanged
+//
+// time: O(w log w) where w is the window size
+func (m *Median[T]) UnmarshalJSON(data []byte) error {
+	var s medianState
+	if err := decodeJSON(data, &s); err != nil {
+		return err
+	}
+	return m.restore(s)
+}
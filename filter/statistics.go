@@ -0,0 +1,255 @@
+package filter
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ========
+// variance
+// ========
+
+// compute a sliding window variance
+//
+// the population variance by default, WithSample switches to the sample variance with
+// bessel's correction
+//
+// the samples are kept in a ring buffer and updated with a sliding form of welford's
+// algorithm, so each sample costs O(1), no memory is allocated after construction and
+// large offsets do not cancel the precision of small variations
+//
+// this type is not safe for concurrent use
+type Variance[T c.Float] struct {
+	window  int
+	sample  bool
+	moments moments
+}
+
+// create a variance filter with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewVariance[T c.Float](windowSize int) *Variance[T] {
+	if windowSize <= 0 {
+		return &Variance[T]{}
+	}
+	return &Variance[T]{window: windowSize, moments: newMoments(windowSize)}
+}
+
+// divide by n-1 instead of n, a window with a single sample then has zero variance
+func (v *Variance[T]) WithSample(sample bool) *Variance[T] {
+	v.sample = sample
+	return v
+}
+
+// reset the stored samples
+func (v *Variance[T]) Reset() {
+	v.moments.reset()
+}
+
+// compute the variance of the current window after inserting value
+//
+// time: O(1)
+func (v *Variance[T]) Compute(value T) T {
+	if v.window <= 0 {
+		return 0
+	}
+
+	v.moments.push(float64(value))
+	return T(v.moments.variance(v.sample))
+}
+
+// ==================
+// standard deviation
+// ==================
+
+// compute a sliding window standard deviation
+//
+// the square root of Variance, with the same population default and WithSample option
+//
+// this type is not safe for concurrent use
+type Std[T c.Float] struct {
+	variance Variance[T]
+}
+
+// create a standard deviation filter with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewStd[T c.Float](windowSize int) *Std[T] {
+	return &Std[T]{variance: *NewVariance[T](windowSize)}
+}
+
+// divide by n-1 instead of n, a window with a single sample then has zero deviation
+func (s *Std[T]) WithSample(sample bool) *Std[T] {
+	s.variance.sample = sample
+	return s
+}
+
+// reset the stored samples
+func (s *Std[T]) Reset() {
+	s.variance.Reset()
+}
+
+// compute the standard deviation of the current window after inserting value
+//
+// time: O(1)
+func (s *Std[T]) Compute(value T) T {
+	return T(math.Sqrt(float64(s.variance.Compute(value))))
+}
+
+// ===
+// rms
+// ===
+
+// compute a sliding window root mean square
+//
+// the running sum of squares is compensated, so it does not drift over long runs
+//
+// this type is not safe for concurrent use
+type Rms[T c.Float] struct {
+	window  int
+	values  ring[T]
+	squares compensated
+}
+
+// create a root mean square filter with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty filter that returns zero
+func NewRms[T c.Float](windowSize int) *Rms[T] {
+	if windowSize <= 0 {
+		return &Rms[T]{}
+	}
+	return &Rms[T]{window: windowSize, values: newRing[T](windowSize)}
+}
+
+// reset the stored samples
+func (r *Rms[T]) Reset() {
+	r.values.reset()
+	r.squares = compensated{}
+}
+
+// compute the root mean square of the current window after inserting value
+//
+// time: O(1)
+func (r *Rms[T]) Compute(value T) T {
+	if r.window <= 0 {
+		return 0
+	}
+
+	if _, old, evicted := r.values.push(value); evicted {
+		r.squares.add(-float64(old) * float64(old))
+	}
+	r.squares.add(float64(value) * float64(value))
+	return T(math.Sqrt(max(0, r.squares.value()) / float64(r.values.n)))
+}
+
+// =====
+// stats
+// =====
+
+// track the mean, variance, standard deviation, root mean square, minimum and maximum of
+// a sliding window at once
+//
+// every statistic is updated in O(1), amortised for the minimum and maximum, and no memory
+// is allocated after construction
+//
+// this type is not safe for concurrent use
+type Stats[T c.Float] struct {
+	window   int
+	moments  moments
+	min, max monotonicDeque[T]
+	lo, hi   T
+}
+
+// create a statistics tracker with a fixed window size
+//
+// if windowSize is less than or equal to zero, it creates an empty tracker that reports zero
+func NewStats[T c.Float](windowSize int) *Stats[T] {
+	if windowSize <= 0 {
+		return &Stats[T]{}
+	}
+	return &Stats[T]{
+		window:  windowSize,
+		moments: newMoments(windowSize),
+		min:     newMonotonicDeque[T](windowSize, false),
+		max:     newMonotonicDeque[T](windowSize, true),
+	}
+}
+
+// reset the stored samples
+func (s *Stats[T]) Reset() {
+	s.moments.reset()
+	s.min.reset()
+	s.max.reset()
+	s.lo, s.hi = 0, 0
+}
+
+// insert value, replacing the oldest sample once the window is full
+//
+// time: O(1) amortised
+func (s *Stats[T]) Add(value T) {
+	if s.window <= 0 {
+		return
+	}
+
+	s.moments.push(float64(value))
+	s.lo = s.min.push(value)
+	s.hi = s.max.push(value)
+}
+
+// return the number of samples in the window
+//
+// time: O(1)
+func (s *Stats[T]) Count() int {
+	return s.moments.ring.n
+}
+
+// return the mean of the window
+//
+// time: O(1)
+func (s *Stats[T]) Mean() T {
+	return T(s.moments.mean())
+}
+
+// return the population variance of the window
+//
+// time: O(1)
+func (s *Stats[T]) Variance() T {
+	return T(s.moments.variance(false))
+}
+
+// return the sample variance of the window, with bessel's correction
+//
+// time: O(1)
+func (s *Stats[T]) SampleVariance() T {
+	return T(s.moments.variance(true))
+}
+
+// return the population standard deviation of the window
+//
+// time: O(1)
+func (s *Stats[T]) Std() T {
+	return T(math.Sqrt(s.moments.variance(false)))
+}
+
+// return the root mean square of the window
+//
+// time: O(1)
+func (s *Stats[T]) Rms() T {
+	mean := s.moments.mean()
+	return T(math.Sqrt(mean*mean + s.moments.variance(false)))
+}
+
+// return the minimum of the window
+//
+// time: O(1)
+func (s *Stats[T]) Min() T {
+	return s.lo
+}
+
+// return the maximum of the window
+//
+// time: O(1)
+func (s *Stats[T]) Max() T {
+	return s.hi
+}
@@ -0,0 +1,275 @@
+package filter
+
+import (
+	c "github.com/vistormu/go-dsa/constraints"
+	"github.com/vistormu/go-dsa/internal/linalg"
+)
+
+// estimate the state of a nonlinear system with an unscented kalman filter
+//
+// model
+//
+//	x_k = f(x_{k-1}, u_k, dt) + w,  w ~ N(0, Q)
+//	z_k = h(x_k) + v,               v ~ N(0, R)
+//
+// instead of linearising, the models are evaluated at 2n+1 sigma points spread around
+// the estimate, which captures the mean and covariance to second order without jacobians
+//
+// the spread is set by alpha, beta and kappa with the scaled unscented transform,
+// by default alpha = 1e-3, beta = 2 (optimal for gaussian priors) and kappa = 0
+//
+// without a process model the state is held constant
+//
+// this type is not safe for concurrent use
+type Ukf[T c.Float] struct {
+	estimate[T]
+
+	f   func(x, u []T, dt T) []T
+	q   linalg.Matrix
+	qFn func(dt T) [][]T
+
+	h func(x []T) []T
+	r linalg.Matrix
+
+	// sigma point weights for the mean and the covariance
+	lambda float64
+	wm, wc []float64
+}
+
+// create an unscented kalman filter from the initial estimate x0 and its covariance p0
+//
+// return ErrDimension if p0 is not len(x0) by len(x0)
+//
+// time: O(n^2)
+func NewUkf[T c.Float](x0 []T, p0 [][]T) (*Ukf[T], error) {
+	e, ok := newEstimate(x0, p0)
+	if !ok {
+		return nil, ErrDimension
+	}
+
+	k := &Ukf[T]{estimate: e, q: linalg.New(e.n, e.n)}
+	k.weights(1e-3, 2, 0)
+	return k, nil
+}
+
+// set the sigma point parameters and return the filter for chaining
+//
+// alpha in (0, 1] scales the spread, beta encodes prior knowledge of the distribution and
+// kappa is a secondary scaling, usually 0 or 3 - n
+//
+// a non positive alpha, or one that makes n + lambda non positive, keeps the previous parameters
+//
+// time: O(n)
+func (k *Ukf[T]) WithSigma(alpha, beta, kappa T) *Ukf[T] {
+	a, n := float64(alpha), float64(k.n)
+	if a <= 0 || a*a*(n+float64(kappa)) <= 0 {
+		return k
+	}
+	k.weights(a, float64(beta), float64(kappa))
+	return k
+}
+
+func (k *Ukf[T]) weights(alpha, beta, kappa float64) {
+	n := float64(k.n)
+	k.lambda = alpha*alpha*(n+kappa) - n
+
+	k.wm = make([]float64, 2*k.n+1)
+	k.wc = make([]float64, 2*k.n+1)
+	k.wm[0] = k.lambda / (n + k.lambda)
+	k.wc[0] = k.wm[0] + 1 - alpha*alpha + beta
+	for i := 1; i <= 2*k.n; i++ {
+		k.wm[i] = 1 / (2 * (n + k.lambda))
+		k.wc[i] = k.wm[i]
+	}
+}
+
+// use the process model f and process noise covariance q
+//
+// return ErrDimension if q is not n by n
+//
+// time: O(n^2)
+func (k *Ukf[T]) Process(f func(x, u []T, dt T) []T, q [][]T) error {
+	qm, ok := k.noise(q)
+	if !ok {
+		return ErrDimension
+	}
+	k.f, k.q, k.qFn = f, qm, nil
+	return nil
+}
+
+// compute the process noise covariance from the timestep of each prediction
+//
+// it replaces the fixed q given to Process, the shape is checked on every Predict
+//
+// time: O(1)
+func (k *Ukf[T]) ProcessNoiseFunc(q func(dt T) [][]T) {
+	k.qFn = q
+}
+
+// use the measurement model h and noise covariance r, m by m, in Update
+//
+// return ErrDimension if r is not square
+//
+// time: O(m^2)
+func (k *Ukf[T]) Measurement(h func(x []T) []T, r [][]T) error {
+	rm, ok := linalg.FromRows(r)
+	if !ok || rm.Rows != rm.Cols {
+		return ErrDimension
+	}
+	k.h, k.r = h, rm
+	return nil
+}
+
+// propagate the estimate over a timestep dt with control input u, which may be nil
+//
+// return ErrDimension if the model outputs do not match the filter and ErrSingular if the
+// covariance is not positive semi definite, leaving the filter unchanged
+//
+// time: O(n^3) plus 2n+1 evaluations of the model
+func (k *Ukf[T]) Predict(u []T, dt T) error {
+	q := k.q
+	if k.qFn != nil {
+		var ok bool
+		if q, ok = k.noise(k.qFn(dt)); !ok {
+			return ErrDimension
+		}
+	}
+
+	if k.f == nil {
+		k.p = symmetric(k.p.Add(q))
+		return nil
+	}
+
+	sigma, ok := k.sigmaPoints()
+	if !ok {
+		return ErrSingular
+	}
+
+	ys := make([][]float64, len(sigma))
+	for i, s := range sigma {
+		ys[i] = toFloats(k.f(fromFloats[T](s, k.n), u, dt))
+		if len(ys[i]) != k.n {
+			return ErrDimension
+		}
+	}
+
+	x := k.mean(ys)
+	p := k.cross(ys, x, ys, x).Add(q)
+
+	k.x, k.p = x, symmetric(p)
+	return nil
+}
+
+// correct the estimate with measurement z using the model set by Measurement
+//
+// return ErrDimension if no measurement model is set or the shapes do not match,
+// and ErrSingular if a covariance cannot be factorised or inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3) plus 2n+1 evaluations of the model
+func (k *Ukf[T]) Update(z []T) error {
+	if k.h == nil {
+		return ErrDimension
+	}
+	return k.update(z, k.h, k.r)
+}
+
+// correct the estimate with measurement z from a sensor with its own model h and noise r
+//
+// return ErrDimension if the shapes do not match and ErrSingular if a covariance cannot be
+// factorised or inverted, leaving the filter unchanged
+//
+// return ErrOutlier if the normalised innovation squared exceeds the threshold set by Gate,
+// leaving the estimate unchanged
+//
+// time: O(n^3 + m^3) plus 2n+1 evaluations of the model
+func (k *Ukf[T]) UpdateWith(z []T, h func(x []T) []T, r [][]T) error {
+	rm, ok := measurementNoise(r, len(z))
+	if !ok || h == nil {
+		return ErrDimension
+	}
+	return k.update(z, h, rm)
+}
+
+func (k *Ukf[T]) update(z []T, h func(x []T) []T, r linalg.Matrix) error {
+	m := len(z)
+	if r.Rows != m {
+		return ErrDimension
+	}
+
+	sigma, ok := k.sigmaPoints()
+	if !ok {
+		return ErrSingular
+	}
+
+	zs := make([][]float64, len(sigma))
+	for i, s := range sigma {
+		zs[i] = toFloats(h(fromFloats[T](s, k.n)))
+		if len(zs[i]) != m {
+			return ErrDimension
+		}
+	}
+
+	zHat := k.mean(zs)
+	s := symmetric(k.cross(zs, zHat, zs, zHat).Add(r))
+	pxz := k.cross(sigma, k.x, zs, zHat)
+
+	y := make([]float64, m)
+	for i := range y {
+		y[i] = float64(z[i]) - zHat[i]
+	}
+
+	return k.correct(y, s, pxz, func(gain linalg.Matrix) linalg.Matrix {
+		// P - K*S*K^t
+		return k.p.Sub(gain.Mul(s).Mul(gain.T()))
+	})
+}
+
+// return the 2n+1 sigma points of the current estimate, false if P cannot be factorised
+func (k *Ukf[T]) sigmaPoints() ([][]float64, bool) {
+	l, ok := k.p.Scale(float64(k.n) + k.lambda).Cholesky()
+	if !ok {
+		return nil, false
+	}
+
+	sigma := make([][]float64, 2*k.n+1)
+	sigma[0] = append([]float64(nil), k.x...)
+	for j := range k.n {
+		plus := make([]float64, k.n)
+		minus := make([]float64, k.n)
+		for i := range k.n {
+			d := l.At(i, j)
+			plus[i] = k.x[i] + d
+			minus[i] = k.x[i] - d
+		}
+		sigma[1+j], sigma[1+k.n+j] = plus, minus
+	}
+	return sigma, true
+}
+
+// return the weighted mean of the transformed sigma points
+func (k *Ukf[T]) mean(ys [][]float64) []float64 {
+	out := make([]float64, len(ys[0]))
+	for i, y := range ys {
+		for j, v := range y {
+			out[j] += k.wm[i] * v
+		}
+	}
+	return out
+}
+
+// return the weighted cross covariance of two sets of sigma points around their means
+func (k *Ukf[T]) cross(a [][]float64, am []float64, b [][]float64, bm []float64) linalg.Matrix {
+	out := linalg.New(len(am), len(bm))
+	for i := range a {
+		for r := range am {
+			da := k.wc[i] * (a[i][r] - am[r])
+			for col := range bm {
+				out.Data[r*out.Cols+col] += da * (b[i][col] - bm[col])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,21 @@
+package filter
+
+import c "github.com/vistormu/go-dsa/constraints"
+
+// convert a slice of numbers to float64
+func toFloats[T c.Number](v []T) []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
+
+// convert a slice of float64 to numbers, with capacity for at least n values
+func fromFloats[T c.Number](v []float64, n int) []T {
+	out := make([]T, len(v), max(n, len(v)))
+	for i, x := range v {
+		out[i] = T(x)
+	}
+	return out
+}
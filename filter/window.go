@@ -0,0 +1,468 @@
+package filter
+
+import (
+	"math"
+
+	c "github.com/vistormu/go-dsa/constraints"
+)
+
+// ====
+// ring
+// ====
+
+// store the last samples of a sliding window in a fixed buffer
+type ring[T any] struct {
+	buf  []T
+	head int // oldest sample
+	n    int
+}
+
+func newRing[T any](capacity int) ring[T] {
+	return ring[T]{buf: make([]T, capacity)}
+}
+
+func (r *ring[T]) full() bool {
+	return r.n == len(r.buf)
+}
+
+// store v and return its slot, together with the value it replaced when the ring was full
+func (r *ring[T]) push(v T) (slot int, old T, evicted bool) {
+	if r.n < len(r.buf) {
+		slot = r.head + r.n
+		if slot >= len(r.buf) {
+			slot -= len(r.buf)
+		}
+		r.n++
+	} else {
+		slot = r.head
+		old, evicted = r.buf[slot], true
+		r.head++
+		if r.head == len(r.buf) {
+			r.head = 0
+		}
+	}
+	r.buf[slot] = v
+	return slot, old, evicted
+}
+
+// return the i-th sample, oldest first
+func (r *ring[T]) at(i int) T {
+	i += r.head
+	if i >= len(r.buf) {
+		i -= len(r.buf)
+	}
+	return r.buf[i]
+}
+
+func (r *ring[T]) reset() {
+	r.head, r.n = 0, 0
+}
+
+// ===========
+// rank window
+// ===========
+
+// track an order statistic of a sliding window with two indexed heaps
+//
+// the lo max heap holds the smallest samples up to the target rank and the hi min heap the rest,
+// each slot of the ring remembers its heap and position so the oldest sample can be replaced in place
+//
+// for a quantile q of n samples, lo holds floor(q*(n-1)) + 1 samples, so its top and the top of hi
+// are the two samples the quantile interpolates between
+type rankWindow[T c.Number] struct {
+	ring ring[T]
+	q    float64
+
+	lo, hi []int
+	pos    []int
+	inLo   []bool
+}
+
+func newRankWindow[T c.Number](capacity int, q float64) rankWindow[T] {
+	return rankWindow[T]{
+		ring: newRing[T](capacity),
+		q:    min(1, max(0, q)),
+		lo:   make([]int, 0, capacity),
+		hi:   make([]int, 0, capacity),
+		pos:  make([]int, capacity),
+		inLo: make([]bool, capacity),
+	}
+}
+
+func (r *rankWindow[T]) reset() {
+	r.ring.reset()
+	r.lo, r.hi = r.lo[:0], r.hi[:0]
+}
+
+// insert v, replacing the oldest sample when full
+//
+// time: O(log w)
+func (r *rankWindow[T]) push(v T) {
+	slot, _, evicted := r.ring.push(v)
+
+	if evicted {
+		// same heap sizes, restore the order inside the heap and then across the split
+		lo := r.inLo[slot]
+		r.up(lo, r.pos[slot])
+		r.down(lo, r.pos[slot])
+
+		if len(r.hi) > 0 && r.value(r.lo[0]) > r.value(r.hi[0]) {
+			a, b := r.lo[0], r.hi[0]
+			r.lo[0], r.hi[0] = b, a
+			r.inLo[a], r.inLo[b] = false, true
+			r.down(true, 0)
+			r.down(false, 0)
+		}
+		return
+	}
+
+	if len(r.lo) > 0 && v > r.value(r.lo[0]) {
+		r.insert(false, slot)
+	} else {
+		r.insert(true, slot)
+	}
+
+	// rebalance to the target rank
+	k := int(math.Floor(r.q*float64(r.ring.n-1))) + 1
+	for len(r.lo) > k {
+		r.insert(false, r.pop(true))
+	}
+	for len(r.lo) < k {
+		r.insert(true, r.pop(false))
+	}
+}
+
+// return the quantile of the window with linear interpolation between samples
+func (r *rankWindow[T]) quantile() float64 {
+	if r.ring.n == 0 {
+		return 0
+	}
+
+	h := r.q * float64(r.ring.n-1)
+	lower := float64(r.value(r.lo[0]))
+	frac := h - math.Floor(h)
+	if frac == 0 || len(r.hi) == 0 {
+		return lower
+	}
+	return lower + frac*(float64(r.value(r.hi[0]))-lower)
+}
+
+func (r *rankWindow[T]) value(slot int) T {
+	return r.ring.buf[slot]
+}
+
+func (r *rankWindow[T]) heap(lo bool) *[]int {
+	if lo {
+		return &r.lo
+	}
+	return &r.hi
+}
+
+// report whether slot a belongs above slot b in the heap
+func (r *rankWindow[T]) above(lo bool, a, b int) bool {
+	if lo {
+		return r.value(a) > r.value(b)
+	}
+	return r.value(a) < r.value(b)
+}
+
+func (r *rankWindow[T]) swap(h []int, i, j int) {
+	h[i], h[j] = h[j], h[i]
+	r.pos[h[i]], r.pos[h[j]] = i, j
+}
+
+func (r *rankWindow[T]) up(lo bool, i int) {
+	h := *r.heap(lo)
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !r.above(lo, h[i], h[parent]) {
+			return
+		}
+		r.swap(h, i, parent)
+		i = parent
+	}
+}
+
+func (r *rankWindow[T]) down(lo bool, i int) {
+	h := *r.heap(lo)
+	for {
+		best := i
+		for _, child := range [2]int{2*i + 1, 2*i + 2} {
+			if child < len(h) && r.above(lo, h[child], h[best]) {
+				best = child
+			}
+		}
+		if best == i {
+			return
+		}
+		r.swap(h, i, best)
+		i = best
+	}
+}
+
+func (r *rankWindow[T]) insert(lo bool, slot int) {
+	h := r.heap(lo)
+	*h = append(*h, slot)
+	r.pos[slot], r.inLo[slot] = len(*h)-1, lo
+	r.up(lo, len(*h)-1)
+}
+
+func (r *rankWindow[T]) pop(lo bool) int {
+	h := r.heap(lo)
+	top := (*h)[0]
+	last := len(*h) - 1
+	r.swap(*h, 0, last)
+	*h = (*h)[:last]
+	r.down(lo, 0)
+	return top
+}
+
+// ===============
+// monotonic deque
+// ===============
+
+// track the minimum or maximum of a sliding window
+//
+// the deque keeps the samples that can still become the extreme, in order of arrival with
+// monotonic values, so the front is always the extreme of the window
+type monotonicDeque[T c.Number] struct {
+	window int
+	max    bool
+	seq    int
+
+	// circular storage of at most window entries
+	idx  []int
+	val  []T
+	head int
+	n    int
+}
+
+func newMonotonicDeque[T c.Number](window int, max bool) monotonicDeque[T] {
+	return monotonicDeque[T]{
+		window: window,
+		max:    max,
+		idx:    make([]int, window),
+		val:    make([]T, window),
+	}
+}
+
+func (d *monotonicDeque[T]) reset() {
+	d.seq, d.head, d.n = 0, 0, 0
+}
+
+// insert v and return the extreme of the last window samples
+//
+// time: O(1) amortised
+func (d *monotonicDeque[T]) push(v T) T {
+	// drop the front once it leaves the window
+	if d.n > 0 && d.idx[d.head] <= d.seq-d.window {
+		d.head = (d.head + 1) % d.window
+		d.n--
+	}
+
+	// drop the samples that v dominates
+	for d.n > 0 {
+		back := (d.head + d.n - 1) % d.window
+		if (d.max && d.val[back] > v) || (!d.max && d.val[back] < v) {
+			break
+		}
+		d.n--
+	}
+
+	back := (d.head + d.n) % d.window
+	d.idx[back], d.val[back] = d.seq, v
+	d.n++
+	d.seq++
+
+	return d.val[d.head]
+}
+
+// ===========
+// compensated
+// ===========
+
+// accumulate a sum with neumaier compensation, so the rounding error stays bounded by the
+// magnitude of the values however many are added and removed
+type compensated struct {
+	sum, comp float64
+}
+
+func (k *compensated) add(v float64) {
+	t := k.sum + v
+	if math.Abs(k.sum) >= math.Abs(v) {
+		k.comp += (k.sum - t) + v
+	} else {
+		k.comp += (v - t) + k.sum
+	}
+	k.sum = t
+}
+
+func (k *compensated) value() float64 {
+	return k.sum + k.comp
+}
+
+// =======
+// moments
+// =======
+
+// track the mean and the sum of squared deviations of a sliding window
+//
+// the sum is compensated and the squared deviations follow the sliding form of welford's update,
+// which subtracts no large squares, so long runs with a large offset keep their precision
+type moments struct {
+	ring  ring[float64]
+	total compensated
+	m2    float64
+}
+
+func newMoments(capacity int) moments {
+	return moments{ring: newRing[float64](capacity)}
+}
+
+func (m *moments) reset() {
+	m.ring.reset()
+	m.total, m.m2 = compensated{}, 0
+}
+
+// insert x, replacing the oldest sample when full
+//
+// time: O(1)
+func (m *moments) push(x float64) {
+	prev := m.mean()
+	_, old, evicted := m.ring.push(x)
+
+	if evicted {
+		m.total.add(-old)
+		m.total.add(x)
+		m.m2 += (x - old) * (x - m.mean() + old - prev)
+	} else {
+		m.total.add(x)
+		m.m2 += (x - prev) * (x - m.mean())
+	}
+	m.m2 = max(0, m.m2)
+}
+
+func (m *moments) mean() float64 {
+	if m.ring.n == 0 {
+		return 0
+	}
+	return m.total.value() / float64(m.ring.n)
+}
+
+// return the population variance, or the sample variance with bessel's correction
+func (m *moments) variance(sample bool) float64 {
+	n := m.ring.n
+	if sample {
+		n--
+	}
+	if n <= 0 {
+		return 0
+	}
+	return m.m2 / float64(n)
+}
+
+// =========================
+// median absolute deviation
+// =========================
+
+// scale of the median absolute deviation that estimates the standard deviation of gaussian noise
+const madScale = 1.4826
+
+// track the median and median absolute deviation of a sliding window
+type madWindow[T c.Number] struct {
+	rank    rankWindow[T]
+	scratch []float64
+}
+
+func newMadWindow[T c.Number](capacity int) madWindow[T] {
+	return madWindow[T]{
+		rank:    newRankWindow[T](capacity, 0.5),
+		scratch: make([]float64, capacity),
+	}
+}
+
+func (m *madWindow[T]) reset() {
+	m.rank.reset()
+}
+
+func (m *madWindow[T]) push(v T) {
+	m.rank.push(v)
+}
+
+func (m *madWindow[T]) median() float64 {
+	return m.rank.quantile()
+}
+
+// return the median absolute deviation from center, scaled to a standard deviation
+//
+// time: O(w) expected
+func (m *madWindow[T]) deviation(center float64) float64 {
+	n := m.rank.ring.n
+	if n == 0 {
+		return 0
+	}
+
+	d := m.scratch[:n]
+	for i := range d {
+		d[i] = math.Abs(float64(m.rank.ring.at(i)) - center)
+	}
+
+	// the upper middle, and for an even count the largest value below it
+	k := n / 2
+	mad := selectKth(d, k)
+	if n%2 == 0 {
+		lower := d[0]
+		for _, v := range d[1:k] {
+			lower = max(lower, v)
+		}
+		mad = (mad + lower) / 2
+	}
+	return madScale * mad
+}
+
+// partially order v so v[k] is its k-th smallest value, with no larger values before it,
+// and return it
+//
+// time: O(n) expected
+func selectKth(v []float64, k int) float64 {
+	lo, hi := 0, len(v)-1
+	for lo < hi {
+		// median of three pivot
+		mid := lo + (hi-lo)/2
+		if v[mid] < v[lo] {
+			v[mid], v[lo] = v[lo], v[mid]
+		}
+		if v[hi] < v[lo] {
+			v[hi], v[lo] = v[lo], v[hi]
+		}
+		if v[hi] < v[mid] {
+			v[hi], v[mid] = v[mid], v[hi]
+		}
+		pivot := v[mid]
+
+		i, j := lo, hi
+		for i <= j {
+			for v[i] < pivot {
+				i++
+			}
+			for v[j] > pivot {
+				j--
+			}
+			if i <= j {
+				v[i], v[j] = v[j], v[i]
+				i++
+				j--
+			}
+		}
+
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return v[k]
+		}
+	}
+	return v[k]
+}
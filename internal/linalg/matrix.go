@@ -0,0 +1,427 @@
+package linalg
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// store a dense row major float64 matrix
+//
+// this type is shared by the control and filter packages and is not part of the public api
+type Matrix struct {
+	Rows, Cols int
+	Data       []float64
+}
+
+// create a zero matrix with the given shape
+//
+// time: O(rows*cols)
+func New(rows, cols int) Matrix {
+	return Matrix{Rows: rows, Cols: cols, Data: make([]float64, rows*cols)}
+}
+
+// create an n by n identity matrix
+//
+// time: O(n^2)
+func Identity(n int) Matrix {
+	m := New(n, n)
+	for i := range n {
+		m.Data[i*n+i] = 1
+	}
+	return m
+}
+
+// create a matrix from a slice of rows
+//
+// return false if the rows are empty or have different lengths
+//
+// time: O(rows*cols)
+func FromRows[T ~float32 | ~float64](rows [][]T) (Matrix, bool) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return Matrix{}, false
+	}
+
+	m := New(len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != m.Cols {
+			return Matrix{}, false
+		}
+		for j, v := range row {
+			m.Data[i*m.Cols+j] = float64(v)
+		}
+	}
+	return m, true
+}
+
+// return a copy of the matrix as a slice of rows
+//
+// time: O(rows*cols)
+func ToRows[T ~float32 | ~float64](m Matrix) [][]T {
+	rows := make([][]T, m.Rows)
+	for i := range m.Rows {
+		rows[i] = make([]T, m.Cols)
+		for j := range m.Cols {
+			rows[i][j] = T(m.Data[i*m.Cols+j])
+		}
+	}
+	return rows
+}
+
+// return the element at row i and column j
+//
+// time: O(1)
+func (m Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+// set the element at row i and column j
+//
+// time: O(1)
+func (m Matrix) Set(i, j int, v float64) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// return a deep copy of the matrix
+//
+// time: O(rows*cols)
+func (m Matrix) Clone() Matrix {
+	out := Matrix{Rows: m.Rows, Cols: m.Cols, Data: make([]float64, len(m.Data))}
+	copy(out.Data, m.Data)
+	return out
+}
+
+// return the transpose
+//
+// time: O(rows*cols)
+func (m Matrix) T() Matrix {
+	out := New(m.Cols, m.Rows)
+	for i := range m.Rows {
+		for j := range m.Cols {
+			out.Data[j*m.Rows+i] = m.Data[i*m.Cols+j]
+		}
+	}
+	return out
+}
+
+// return m + o
+//
+// time: O(rows*cols)
+func (m Matrix) Add(o Matrix) Matrix {
+	out := m.Clone()
+	for i, v := range o.Data {
+		out.Data[i] += v
+	}
+	return out
+}
+
+// return m - o
+//
+// time: O(rows*cols)
+func (m Matrix) Sub(o Matrix) Matrix {
+	out := m.Clone()
+	for i, v := range o.Data {
+		out.Data[i] -= v
+	}
+	return out
+}
+
+// return s*m
+//
+// time: O(rows*cols)
+func (m Matrix) Scale(s float64) Matrix {
+	out := m.Clone()
+	for i := range out.Data {
+		out.Data[i] *= s
+	}
+	return out
+}
+
+// return the matrix product m*o
+//
+// time: O(n*m*p)
+func (m Matrix) Mul(o Matrix) Matrix {
+	out := New(m.Rows, o.Cols)
+	for i := range m.Rows {
+		for k := range m.Cols {
+			a := m.Data[i*m.Cols+k]
+			if a == 0 {
+				continue
+			}
+			for j := range o.Cols {
+				out.Data[i*o.Cols+j] += a * o.Data[k*o.Cols+j]
+			}
+		}
+	}
+	return out
+}
+
+// write m*v into out
+//
+// out must not alias v
+//
+// time: O(rows*cols)
+func (m Matrix) MulVec(v, out []float64) {
+	for i := range m.Rows {
+		var acc float64
+		row := m.Data[i*m.Cols : (i+1)*m.Cols]
+		for j, a := range row {
+			acc += a * v[j]
+		}
+		out[i] = acc
+	}
+}
+
+// return the largest absolute row sum
+//
+// time: O(rows*cols)
+func (m Matrix) NormInf() float64 {
+	var n float64
+	for i := range m.Rows {
+		var s float64
+		for j := range m.Cols {
+			s += math.Abs(m.Data[i*m.Cols+j])
+		}
+		n = max(n, s)
+	}
+	return n
+}
+
+// report whether every value is finite
+//
+// time: O(n)
+func Finite(v []float64) bool {
+	for _, x := range v {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// solve m*x = b for x using gaussian elimination with partial pivoting
+//
+// return false if m is not square or is singular
+//
+// time: O(n^3)
+func (m Matrix) Solve(b Matrix) (Matrix, bool) {
+	n := m.Rows
+	if n != m.Cols || b.Rows != n {
+		return Matrix{}, false
+	}
+
+	a := m.Clone()
+	x := b.Clone()
+
+	for col := range n {
+		// pick the largest pivot for stability
+		p := col
+		best := math.Abs(a.Data[col*n+col])
+		for r := col + 1; r < n; r++ {
+			if v := math.Abs(a.Data[r*n+col]); v > best {
+				best, p = v, r
+			}
+		}
+		if best == 0 || math.IsNaN(best) {
+			return Matrix{}, false
+		}
+
+		if p != col {
+			swapRows(a, p, col)
+			swapRows(x, p, col)
+		}
+
+		piv := a.Data[col*n+col]
+		for r := col + 1; r < n; r++ {
+			f := a.Data[r*n+col] / piv
+			if f == 0 {
+				continue
+			}
+			for c := col; c < n; c++ {
+				a.Data[r*n+c] -= f * a.Data[col*n+c]
+			}
+			for c := range x.Cols {
+				x.Data[r*x.Cols+c] -= f * x.Data[col*x.Cols+c]
+			}
+		}
+	}
+
+	// back substitution
+	for r := n - 1; r >= 0; r-- {
+		piv := a.Data[r*n+r]
+		for c := range x.Cols {
+			s := x.Data[r*x.Cols+c]
+			for k := r + 1; k < n; k++ {
+				s -= a.Data[r*n+k] * x.Data[k*x.Cols+c]
+			}
+			x.Data[r*x.Cols+c] = s / piv
+		}
+	}
+
+	return x, true
+}
+
+// return the inverse of a square matrix
+//
+// return false if m is singular
+//
+// time: O(n^3)
+func (m Matrix) Inverse() (Matrix, bool) {
+	return m.Solve(Identity(m.Rows))
+}
+
+// return the lower triangular l with l*l^t = m for a symmetric positive semi definite m
+//
+// pivots that vanish within rounding leave their column at zero, so singular covariances
+// are accepted
+//
+// return false if m is not square or has a clearly negative pivot
+//
+// time: O(n^3)
+func (m Matrix) Cholesky() (Matrix, bool) {
+	n := m.Rows
+	if n != m.Cols {
+		return Matrix{}, false
+	}
+
+	scale := 0.0
+	for i := range n {
+		scale = max(scale, math.Abs(m.Data[i*n+i]))
+	}
+	tol := 1e-12 * max(scale, 1e-300)
+
+	l := New(n, n)
+	for j := range n {
+		d := m.Data[j*n+j]
+		for k := range j {
+			d -= l.Data[j*n+k] * l.Data[j*n+k]
+		}
+		if d < -tol || math.IsNaN(d) {
+			return Matrix{}, false
+		}
+		if d <= tol {
+			continue
+		}
+
+		piv := math.Sqrt(d)
+		l.Data[j*n+j] = piv
+		for i := j + 1; i < n; i++ {
+			s := m.Data[i*n+j]
+			for k := range j {
+				s -= l.Data[i*n+k] * l.Data[j*n+k]
+			}
+			l.Data[i*n+j] = s / piv
+		}
+	}
+	return l, true
+}
+
+// return the matrix exponential e^m
+//
+// use scaling and squaring with a degree 6 pade approximant
+//
+// time: O(n^3 log ||m||)
+func Expm(m Matrix) Matrix {
+	n := m.Rows
+
+	// scale so that the norm is below 0.5
+	s := 0
+	if norm := m.NormInf(); norm > 0.5 {
+		s = max(0, int(math.Ceil(math.Log2(norm/0.5))))
+	}
+	a := m.Scale(1 / math.Pow(2, float64(s)))
+
+	const q = 6
+	c := 0.5
+	x := a.Clone()
+	num := Identity(n).Add(a.Scale(c))
+	den := Identity(n).Sub(a.Scale(c))
+
+	sign := 1.0
+	for k := 2; k <= q; k++ {
+		c = c * float64(q-k+1) / float64(k*(2*q-k+1))
+		x = a.Mul(x)
+		cx := x.Scale(c)
+		num = num.Add(cx)
+		if sign > 0 {
+			den = den.Add(cx)
+		} else {
+			den = den.Sub(cx)
+		}
+		sign = -sign
+	}
+
+	e, ok := den.Solve(num)
+	if !ok {
+		return Identity(n)
+	}
+
+	for range s {
+		e = e.Mul(e)
+	}
+	return e
+}
+
+func swapRows(m Matrix, i, j int) {
+	ri := m.Data[i*m.Cols : (i+1)*m.Cols]
+	rj := m.Data[j*m.Cols : (j+1)*m.Cols]
+	for k := range ri {
+		ri[k], rj[k] = rj[k], ri[k]
+	}
+}
+
+// solve (s*I - a)*x = b for x over the complex numbers
+//
+// return false if s is an eigenvalue of a
+//
+// time: O(n^3)
+func Resolvent(a Matrix, s complex128, b []float64) ([]complex128, bool) {
+	n := a.Rows
+	m := make([]complex128, n*n)
+	x := make([]complex128, n)
+	for i := range n {
+		for j := range n {
+			m[i*n+j] = complex(-a.Data[i*n+j], 0)
+		}
+		m[i*n+i] += s
+		x[i] = complex(b[i], 0)
+	}
+
+	for col := range n {
+		p := col
+		best := cmplx.Abs(m[col*n+col])
+		for r := col + 1; r < n; r++ {
+			if v := cmplx.Abs(m[r*n+col]); v > best {
+				best, p = v, r
+			}
+		}
+		if best == 0 {
+			return nil, false
+		}
+
+		if p != col {
+			for k := range n {
+				m[p*n+k], m[col*n+k] = m[col*n+k], m[p*n+k]
+			}
+			x[p], x[col] = x[col], x[p]
+		}
+
+		piv := m[col*n+col]
+		for r := col + 1; r < n; r++ {
+			f := m[r*n+col] / piv
+			for k := col; k < n; k++ {
+				m[r*n+k] -= f * m[col*n+k]
+			}
+			x[r] -= f * x[col]
+		}
+	}
+
+	for r := n - 1; r >= 0; r-- {
+		acc := x[r]
+		for k := r + 1; k < n; k++ {
+			acc -= m[r*n+k] * x[k]
+		}
+		x[r] = acc / m[r*n+r]
+	}
+
+	return x, true
+}
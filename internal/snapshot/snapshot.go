@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+)
+
+// returned when a snapshot is truncated, has trailing data or belongs to another kind
+var ErrInvalid = errors.New("snapshot: invalid data")
+
+const (
+	magic   = "GDSS"
+	version = 1
+)
+
+// encode the exported fields of the struct v in declaration order
+//
+// supported field kinds are float64, int, bool and []float64
+//
+// the output starts with a header holding a format version and kind, so Decode
+// can reject snapshots of another type
+//
+// used by the control and filter packages for their snapshots, not part of the public api
+//
+// time: O(size of v)
+func Encode(kind string, v any) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, magic...)
+	buf = append(buf, version)
+	buf = binary.AppendUvarint(buf, uint64(len(kind)))
+	buf = append(buf, kind...)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	for i := range rv.NumField() {
+		f := rv.Field(i)
+		switch f.Kind() {
+		case reflect.Float64:
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f.Float()))
+		case reflect.Int:
+			buf = binary.AppendVarint(buf, f.Int())
+		case reflect.Bool:
+			b := byte(0)
+			if f.Bool() {
+				b = 1
+			}
+			buf = append(buf, b)
+		case reflect.Slice:
+			buf = binary.AppendUvarint(buf, uint64(f.Len()))
+			for j := range f.Len() {
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f.Index(j).Float()))
+			}
+		default:
+			panic("snapshot: unsupported field " + rv.Type().Field(i).Name)
+		}
+	}
+
+	return buf
+}
+
+// decode data produced by Encode for the same kind into the struct pointed to by v
+//
+// return ErrInvalid if the header does not match, the data is truncated or has trailing bytes
+//
+// time: O(len(data))
+func Decode(data []byte, kind string, v any) error {
+	d := decoder{data: data}
+
+	if string(d.bytes(len(magic))) != magic || d.byte() != version {
+		return ErrInvalid
+	}
+	n, ok := d.uvarint()
+	if !ok || n > uint64(len(d.data)) || string(d.bytes(int(n))) != kind {
+		return ErrInvalid
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	for i := range rv.NumField() {
+		f := rv.Field(i)
+		switch f.Kind() {
+		case reflect.Float64:
+			f.SetFloat(d.float())
+		case reflect.Int:
+			x, ok := binary.Varint(d.data)
+			if ok <= 0 {
+				return ErrInvalid
+			}
+			d.data = d.data[ok:]
+			f.SetInt(x)
+		case reflect.Bool:
+			f.SetBool(d.byte() == 1)
+		case reflect.Slice:
+			n, ok := d.uvarint()
+			if !ok || n > uint64(len(d.data)/8) {
+				return ErrInvalid
+			}
+			s := make([]float64, n)
+			for j := range s {
+				s[j] = d.float()
+			}
+			f.Set(reflect.ValueOf(s))
+		default:
+			panic("snapshot: unsupported field " + rv.Type().Field(i).Name)
+		}
+	}
+
+	if d.bad || len(d.data) != 0 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// read values from a byte slice, flagging any out of range read
+type decoder struct {
+	data []byte
+	bad  bool
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if n > len(d.data) {
+		d.bad = true
+		d.data = nil
+		return nil
+	}
+	out := d.data[:n]
+	d.data = d.data[n:]
+	return out
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) float() float64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (d *decoder) uvarint() (uint64, bool) {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.bad = true
+		return 0, false
+	}
+	d.data = d.data[n:]
+	return x, true
+}